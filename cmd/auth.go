@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
+	"golang.org/x/term"
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the GitHub tokens used by vrsr",
+	Long: "Tokens raise the GitHub API rate limit from 60 to 5,000 calls per hour. For each GitHub host, the first token found along this chain is used:\n\n" +
+		"  1. the \"github.tokens\" setting, by host;\n" +
+		"  2. the VRSR_GITHUB_TOKEN, GITHUB_TOKEN and GH_TOKEN environment variables for github.com, GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN for other hosts;\n" +
+		"  3. the token saved by `vrsr auth login` in ~/.vrsr/credentials.yaml;\n" +
+		"  4. the hosts.yml file of the gh CLI;\n" +
+		"  5. the ~/.netrc file (or $NETRC).",
+}
+
+// authLoginCmd represents the auth login command
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Save a GitHub token",
+	Long: "Reads a token from the terminal, or from the standard input when it is not a terminal " +
+		"(e.g. `vrsr auth login < token.txt`), checks it against the GitHub host and saves it in ~/.vrsr/credentials.yaml, readable by the current user only.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return authLogin(cmd)
+	},
+}
+
+// authStatusCmd represents the auth status command
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the token in effect for each GitHub host",
+	Long: "Shows, for github.com and every GitHub Enterprise host in use, where the token in effect comes from " +
+		"and the remaining API rate limit.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return authStatus(cmd)
+	},
+}
+
+func init() {
+	authLoginCmd.Flags().String("host", "github.com", "GitHub host the token is for")
+	if err := viper.BindPFlag("auth.login.host", authLoginCmd.Flags().Lookup("host")); err != nil {
+		authLoginCmd.PrintErr(err)
+		panic(err)
+	}
+	authStatusCmd.Flags().StringP("output", "o", common.OutputText, fmt.Sprintf("Output format: %s, %s or %s", common.OutputText, common.OutputJSON, common.OutputYAML))
+	if err := viper.BindPFlag("auth.status.output", authStatusCmd.Flags().Lookup("output")); err != nil {
+		authStatusCmd.PrintErr(err)
+		panic(err)
+	}
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+// AuthStatus is the authentication state of a GitHub host
+type AuthStatus struct {
+	Host string `json:"host" yaml:"host"`
+	// Source is where the token comes from, empty when the host is accessed anonymously
+	Source    string            `json:"source" yaml:"source"`
+	RateLimit *github.RateLimit `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	Error     string            `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// githubHosts returns a helper for github.com and for every GitHub host the tools are released on, by host
+func githubHosts() (map[string]github.GithubHelper, error) {
+	public := github.New(nil)
+	helpers := map[string]github.GithubHelper{github.Host(public.Client.BaseURL): public}
+	confs := []github.RepoConfDef{{}}
+	for _, td := range toolDefs {
+		confs = append(confs, td.RepoConf())
+	}
+	for _, rc := range confs {
+		ghc, err := github.NewFor(rc)
+		if err != nil {
+			return nil, err
+		}
+		helpers[github.Host(ghc.Client.BaseURL)] = ghc
+	}
+	return helpers, nil
+}
+
+// authLogin reads, checks and saves a token for the host
+func authLogin(cmd *cobra.Command) error {
+	host := strings.ToLower(viper.GetString("auth.login.host"))
+	helpers, err := githubHosts()
+	if err != nil {
+		return err
+	}
+	ghc, ok := helpers[host]
+	if !ok {
+		if ghc, err = github.NewFor(github.RepoConfDef{BaseURL: "https://" + host + "/api/v3/"}); err != nil {
+			return err
+		}
+	}
+	token, err := readToken(cmd, host)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("no token given")
+	}
+	if err := utils.CheckOnline("check the token"); err != nil {
+		return err
+	}
+	authenticated := ghc.WithToken(token)
+	login, err := authenticated.Login(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("token rejected by %s: %w", host, err)
+	}
+	p, err := github.SaveToken(host, token)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("Logged in to %s as %s, token saved to %s\n", host, login, p)
+	if c, _ := github.FindCredential(host); c.Source != p {
+		cmd.Printf("Note: the token from %s takes precedence over it\n", c.Source)
+	}
+	return nil
+}
+
+// readToken reads the token from the terminal without echoing it, or from the standard input
+func readToken(cmd *cobra.Command, host string) (string, error) {
+	in := cmd.InOrStdin()
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		cmd.PrintErrf("Paste a token for %s: ", host)
+		token, err := term.ReadPassword(int(f.Fd()))
+		cmd.PrintErrln()
+		return strings.TrimSpace(string(token)), err
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// authStatus prints the token source and rate limit of each GitHub host
+func authStatus(cmd *cobra.Command) error {
+	format, err := common.ValidateOutputFormat(viper.GetString("auth.status.output"))
+	if err != nil {
+		return err
+	}
+	helpers, err := githubHosts()
+	if err != nil {
+		return err
+	}
+	hosts := make([]string, 0, len(helpers))
+	for host := range helpers {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+
+	statuses := make([]AuthStatus, 0, len(hosts))
+	for _, host := range hosts {
+		st := AuthStatus{Host: host}
+		if c, ok := github.FindCredential(host); ok {
+			st.Source = c.Source
+		}
+		if utils.IsOffline() {
+			st.Error = utils.ErrOffline.Error()
+		} else {
+			ghc := helpers[host]
+			if st.RateLimit, err = ghc.RateLimit(cmd.Context()); err != nil {
+				st.Error = err.Error()
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	if format != common.OutputText {
+		return common.PrintOutput(cmd.OutOrStdout(), format, statuses)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOST\tTOKEN\tRATE LIMIT\tRESET")
+	for _, st := range statuses {
+		source := st.Source
+		if source == "" {
+			source = "none (anonymous)"
+		}
+		limit, reset := "unlimited", "-"
+		switch {
+		case st.Error != "":
+			limit = "unknown: " + st.Error
+		case st.RateLimit != nil:
+			limit = fmt.Sprintf("%d/%d remaining", st.RateLimit.Remaining, st.RateLimit.Limit)
+			reset = humanize.Time(st.RateLimit.Reset)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", st.Host, source, limit, reset)
+	}
+	return w.Flush()
+}
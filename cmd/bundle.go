@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/bundle"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Carry tool versions to machines without network access",
+	Long: "A bundle is a tarball holding tool binaries for one or more platforms, as downloaded upstream, " +
+		"along with their SHA-256 checksums and the releases cache of the tools.\n\n" +
+		"Create it with `vrsr bundle create` on a connected machine, then run `vrsr bundle import` on the disconnected one.",
+}
+
+// bundleCreateCmd represents the bundle create command
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Download tool versions into a bundle",
+	Long: fmt.Sprintf("Downloads the given tool versions for every platform and writes them into a bundle, e.g.:\n\n"+
+		"  vrsr bundle create --tools kubectl@1.30.2,talosctl@v1.8.0 --platform linux/amd64,linux/arm64 -o bundle.tar\n\n"+
+		"Versions may be aliases, partial versions or constraints. A tool given without version uses the one pinned by the nearest %s.\n\n"+
+		"Artifacts are verified against their published checksums, or against the checksums locked by the nearest %s. "+
+		"An artifact with neither is refused unless --skip-verify is given.", project.FileName, project.LockFileName),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return createBundle(cmd)
+	},
+}
+
+// bundleImportCmd represents the bundle import command
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Install the tool versions of a bundle",
+	Long: "Installs into the vrs-path the tool versions of the bundle built for the current platform, verifying their checksums, " +
+		"and restores the releases cache of the tools unless the local one is more recent.\n\n" +
+		"No network access is needed, the installed versions can then be activated with `vrsr <tool> use <version>`.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return importBundle(cmd, args[0])
+	},
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringSlice("tools", nil, "Tool versions to bundle, as tool@version (repeatable)")
+	bundleCreateCmd.Flags().StringSlice("platform", []string{project.Platform(runtime.GOOS, runtime.GOARCH)}, "Platforms to bundle, as os/arch (repeatable)")
+	bundleCreateCmd.Flags().StringP("output", "o", "vrsr-bundle.tar", "Path of the bundle to write")
+	bundleCreateCmd.Flags().Bool("skip-verify", false, "Bundle artifacts with neither a published nor a locked checksum (unsafe)")
+	if err := bundleCreateCmd.MarkFlagRequired("tools"); err != nil {
+		bundleCreateCmd.PrintErr(err)
+		panic(err)
+	}
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+// createBundle downloads the requested tool versions and writes the bundle
+func createBundle(cmd *cobra.Command) error {
+	specs, err := cmd.Flags().GetStringSlice("tools")
+	if err != nil {
+		return err
+	}
+	platforms, err := cmd.Flags().GetStringSlice("platform")
+	if err != nil {
+		return err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	skipVerify, err := cmd.Flags().GetBool("skip-verify")
+	if err != nil {
+		return err
+	}
+	lock, err := project.FindLock()
+	if err != nil {
+		return err
+	}
+
+	// write next to the destination, so that a failure never leaves a partial bundle behind
+	f, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	bw := bundle.NewWriter(f)
+	for _, spec := range specs {
+		tool, vrs, _ := strings.Cut(strings.TrimSpace(spec), "@")
+		td, ok := toolDefs[tool]
+		if !ok {
+			_ = f.Close()
+			return fmt.Errorf("unknown tool %q", tool)
+		}
+		if vrs == "" {
+			if vrs, _ = project.PinnedVersion(tool); vrs == "" {
+				_ = f.Close()
+				return fmt.Errorf("no version given for %s and none pinned: use %s@<version>", tool, tool)
+			}
+		}
+		lt, _ := lock.Tool(tool)
+		if err := addToBundle(cmd, bw, td.RepoConf(), tool, vrs, platforms, lt, skipVerify); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("%s %s: %w", tool, vrs, err)
+		}
+	}
+	err = bw.Close()
+	if err1 := f.Close(); err == nil && err1 != nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(f.Name(), output); err != nil {
+		return err
+	}
+	cmd.Printf("Wrote bundle %s\n", output)
+	return nil
+}
+
+// addToBundle adds the artifacts of the tool version for every platform, and the releases cache of the tool.
+// The artifacts of the tag locked in lt are verified against their locked checksums.
+func addToBundle(cmd *cobra.Command, bw *bundle.Writer, repoConf github.RepoConfDef, tool, vrs string, platforms []string, lt project.LockedTool, skipVerify bool) error {
+	ghc, err := github.NewFor(repoConf)
+	if err != nil {
+		return err
+	}
+	tag, err := common.ResolveRemoteVersion(cmd, vrs, tool, repoConf)
+	if err != nil {
+		return err
+	}
+	for _, platform := range platforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		data, err := github.NewPlatformTemplateData(tool, tag, platform)
+		if err != nil {
+			return err
+		}
+		archive, err := repoConf.ArchiveSpec(data)
+		if err != nil {
+			return err
+		}
+		opts := github.FetchArtifactOptions{SkipVerify: skipVerify}
+		if lt.Tag == tag {
+			opts.Checksum = lt.Platforms[platform].SHA256
+		}
+		a, err := addArtifact(cmd.Context(), bw, &ghc, repoConf, bundle.Artifact{
+			Tool:        tool,
+			Tag:         tag,
+			Platform:    platform,
+			ArchiveType: archive.Type,
+			BinaryPath:  archive.BinaryPath,
+		}, opts)
+		if errors.Is(err, utils.ErrChecksumNotFound) {
+			return fmt.Errorf("%s: %w (use --skip-verify to bundle it anyway)", platform, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", platform, err)
+		}
+		if !a.Verified {
+			cmd.PrintErrf("Warning: no published or locked checksum for %s %s on %s, its checksum was only computed\n", tool, tag, platform)
+		}
+		cmd.Printf("Added %s %s for %s\n", tool, tag, platform)
+	}
+
+	// the disconnected machine lists and resolves the versions of the tool from the bundled cache
+	if _, err := ghc.FetchAllReleases(cmd.Context(), tool, github.FetchOptions{IncludeDevel: true, RepoConf: repoConf}); err != nil {
+		return fmt.Errorf("failed to fetch the releases: %w", err)
+	}
+	cachePath, err := utils.GetCachePath(tool)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no releases cache of %s to bundle", tool)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.AddCache(tool, content)
+}
+
+// addArtifact downloads the artifact to a temp file, as its size must be known before adding it,
+// and returns it as added to the bundle
+func addArtifact(ctx context.Context, bw *bundle.Writer, ghc *github.GithubHelper, repoConf github.RepoConfDef, a bundle.Artifact, opts github.FetchArtifactOptions) (bundle.Artifact, error) {
+	tmpFile, err := os.CreateTemp("", "vrsr-artifact-*")
+	if err != nil {
+		return a, err
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	pa, err := ghc.FetchArtifact(ctx, a.Tool, a.Tag, repoConf, a.Platform, opts, tmpFile)
+	if err != nil {
+		return a, err
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return a, err
+	}
+	a.SourceURL, a.SHA256, a.Verified = pa.URL, pa.SHA256, pa.Verified
+	return a, bw.AddArtifact(a, tmpFile)
+}
+
+// importBundle installs the tool versions of the bundle for the current platform
+func importBundle(cmd *cobra.Command, bundlePath string) error {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	dir, err := os.MkdirTemp("", "vrsr-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	m, err := bundle.Extract(f, dir)
+	if err != nil {
+		return err
+	}
+
+	platform := project.Platform(runtime.GOOS, runtime.GOARCH)
+	installed, err := m.Install(cmd.Context(), cmd.ErrOrStderr(), dir, viper.GetString("vrs-path"), platform)
+	for _, a := range installed {
+		cmd.Printf("Installed %s %s\n", a.Tool, a.Tag)
+	}
+	if err != nil {
+		return err
+	}
+	if len(installed) == 0 {
+		cmd.Printf("No new version to install for %s in the bundle\n", platform)
+	}
+	restored, err := m.RestoreCaches(cmd.Context(), cmd.ErrOrStderr(), dir)
+	for _, tool := range restored {
+		cmd.Printf("Restored the releases cache of %s\n", tool)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/shim"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [--bin-path <path>] [--vrs-path <path>] <tool> [args...]",
+	Short: "Run the version of a tool selected for the current context",
+	Long: "Runs the tool version selected, in order of precedence, by the VRSR_<TOOL>_VERSION environment variable, " +
+		"the nearest project file or the global version.\n\n" +
+		"This is what the shims created in shim mode call, all the arguments after the tool name are passed to it untouched. " +
+		"Only --bin-path and --vrs-path are accepted, before the tool name.",
+	Args: cobra.MinimumNArgs(1),
+	// every argument belongs to the executed tool
+	DisableFlagParsing: true,
+	SilenceUsage:       true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, args, err := execPaths(args)
+		if err != nil {
+			return err
+		}
+		return execTool(paths["bin-path"], paths["vrs-path"], args[0], args[1:])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+}
+
+// execPaths splits the --bin-path and --vrs-path flags given before the tool name, as the shims do,
+// from the arguments. The paths missing from the flags are the configured ones.
+func execPaths(args []string) (map[string]string, []string, error) {
+	paths := map[string]string{"bin-path": viper.GetString("bin-path"), "vrs-path": viper.GetString("vrs-path")}
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[0], "--"), "=")
+		if _, ok := paths[name]; !ok || !strings.HasPrefix(args[0], "--") {
+			break
+		}
+		args = args[1:]
+		if !hasValue {
+			if len(args) == 0 {
+				return nil, nil, fmt.Errorf("flag --%s needs a value", name)
+			}
+			value, args = args[0], args[1:]
+		}
+		paths[name] = value
+	}
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("the tool to run is missing")
+	}
+	return paths, args, nil
+}
+
+// execTool resolves the version of the tool and replaces the current process with it
+func execTool(binPath, vrsPath, tool string, args []string) error {
+	vrs, source, err := shim.Resolve(binPath, tool)
+	if err != nil {
+		return err
+	}
+	binary, err := shim.BinaryPath(vrsPath, tool, vrs)
+	if err != nil {
+		return fmt.Errorf("%w (selected by %s)", err, source)
+	}
+	return shim.Exec(binary, append([]string{tool}, args...))
+}
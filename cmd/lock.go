@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+)
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the project lockfile",
+	Long: fmt.Sprintf("The %s file, stored next to %s, records the exact tag, download URL and SHA-256 "+
+		"of every pinned tool for several platforms, so that every developer and CI runner installs byte-identical binaries.\n\n"+
+		"Installing a locked version fails when the download does not match the lockfile.", project.LockFileName, project.FileName),
+}
+
+// lockUpdateCmd represents the lock update command
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update [tool...]",
+	Short: "Resolve the pinned versions and refresh the lockfile",
+	Long: fmt.Sprintf("Resolves the versions pinned by the nearest %s and records their artifacts in %s.\n\n"+
+		"Without arguments every pinned tool is locked again, otherwise only the given ones.\n\n"+
+		"Platforms already in the lockfile are kept, new ones are added with --platform. "+
+		"A new lockfile covers %v unless the \"lock.platforms\" setting says otherwise.",
+		project.FileName, project.LockFileName, project.DefaultPlatforms),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateLock(cmd, args)
+	},
+}
+
+func init() {
+	lockUpdateCmd.Flags().StringSlice("platform", nil, "Additional platform to lock, as os/arch (repeatable)")
+	lockUpdateCmd.Flags().Bool("refresh", false, "Refresh the releases cache before resolving the versions")
+	if err := viper.BindPFlag("lock.update.refresh", lockUpdateCmd.Flags().Lookup("refresh")); err != nil {
+		lockUpdateCmd.PrintErr(err)
+		panic(err)
+	}
+	lockCmd.AddCommand(lockUpdateCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+// updateLock resolves the pinned versions of the given tools (all when empty) and writes the lockfile
+func updateLock(cmd *cobra.Command, names []string) error {
+	f, err := project.FindFromWd()
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return fmt.Errorf("no %s file found in the current directory or any parent", project.FileName)
+	}
+	lockPath := project.LockPath(f.Path)
+	lock, err := project.LoadLock(lockPath)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		lock = &project.Lock{Path: lockPath, Tools: map[string]project.LockedTool{}}
+	}
+	if len(names) == 0 {
+		names = f.ToolNames()
+		// forget the tools no longer pinned
+		for tool := range lock.Tools {
+			if _, ok := f.Version(tool); !ok {
+				delete(lock.Tools, tool)
+			}
+		}
+	}
+	extra, err := cmd.Flags().GetStringSlice("platform")
+	if err != nil {
+		return err
+	}
+	platforms := lockPlatforms(lock, extra)
+
+	var errs []error
+	for _, tool := range names {
+		vrs, ok := f.Version(tool)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s is not pinned by %s", tool, f.Path))
+			continue
+		}
+		td, ok := toolDefs[tool]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown tool %q", tool))
+			continue
+		}
+		repoConf := td.RepoConf()
+		ghc, err := github.NewFor(repoConf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tool, err))
+			continue
+		}
+		if viper.GetBool("lock.update.refresh") {
+			if _, err := ghc.FetchAllReleases(cmd.Context(), tool, github.FetchOptions{IncludeDevel: true, Force: true, RepoConf: repoConf}); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", tool, err))
+				continue
+			}
+		}
+		tag, err := common.ResolveRemoteVersion(cmd, vrs, tool, repoConf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", tool, vrs, err))
+			continue
+		}
+		artifacts, err := ghc.ReleaseArtifacts(cmd.Context(), tool, tag, repoConf, platforms)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", tool, tag, err))
+			continue
+		}
+		lt := project.LockedTool{Version: vrs, Tag: tag, Platforms: map[string]project.LockedArtifact{}}
+		for platform, a := range artifacts {
+			lt.Platforms[platform] = project.LockedArtifact{URL: a.URL, SHA256: a.SHA256}
+		}
+		lock.Tools[tool] = lt
+		cmd.Printf("Locked %s %s to %s for %d platforms\n", tool, vrs, tag, len(platforms))
+	}
+	if len(errs) > 0 {
+		// a partially refreshed lockfile would mix old and new resolutions
+		return fmt.Errorf("lockfile not updated: %w", errors.Join(errs...))
+	}
+	if err := lock.Save(); err != nil {
+		return err
+	}
+	cmd.Printf("Wrote %s\n", lock.Path)
+	return nil
+}
+
+// lockPlatforms returns the platforms to lock: the ones already in the lockfile, or the
+// configured defaults for a new one, plus the extra ones requested.
+func lockPlatforms(lock *project.Lock, extra []string) []string {
+	var platforms []string
+	for _, lt := range lock.Tools {
+		platforms = append(platforms, lt.PlatformNames()...)
+	}
+	if len(platforms) == 0 {
+		platforms = viper.GetStringSlice("lock.platforms")
+	}
+	if len(platforms) == 0 {
+		platforms = project.DefaultPlatforms
+	}
+	platforms = append(slices.Clone(platforms), extra...)
+	for i, p := range platforms {
+		platforms[i] = strings.ToLower(strings.TrimSpace(p))
+	}
+	slices.Sort(platforms)
+	return slices.Compact(platforms)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/project"
+)
+
+// outdatedCmd represents the outdated command
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show the upgrades available for the tools in use",
+	Long: fmt.Sprintf("Compares the active version of every tool against its releases and shows the newest version "+
+		"on the same minor series (patch), on the same major version (minor) and overall (major).\n\n"+
+		"Releases are read from the releases cache when available, run `vrsr <tool> list-remote -f` to refresh it.\n\n"+
+		"Tools pinned by the nearest %s show the pin, `vrsr upgrade` does not leave it.", project.FileName),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return outdated(cmd)
+	},
+}
+
+func init() {
+	outdatedCmd.Flags().StringP("output", "o", common.OutputText, fmt.Sprintf("Output format: %s, %s or %s", common.OutputText, common.OutputJSON, common.OutputYAML))
+	if err := viper.BindPFlag("outdated.output", outdatedCmd.Flags().Lookup("output")); err != nil {
+		outdatedCmd.PrintErr(err)
+		panic(err)
+	}
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+// outdated prints the upgrades available for every tool in use
+func outdated(cmd *cobra.Command) error {
+	format, err := common.ValidateOutputFormat(viper.GetString("outdated.output"))
+	if err != nil {
+		return err
+	}
+	reports := []common.Outdated{}
+	for _, c := range rootCmd.Commands() {
+		td, ok := toolDefs[c.Name()]
+		if !ok {
+			continue
+		}
+		o, err := common.GetOutdated(cmd.Context(), td.Name, td.RepoConf())
+		if err != nil {
+			return fmt.Errorf("%s: %w", td.Name, err)
+		}
+		if o.Current == "" {
+			// nothing in use, nothing to upgrade
+			continue
+		}
+		reports = append(reports, o)
+	}
+	if format != common.OutputText {
+		return common.PrintOutput(cmd.OutOrStdout(), format, reports)
+	}
+	if len(reports) == 0 {
+		cmd.Println("No tool in use.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TOOL\tCURRENT\tPATCH\tMINOR\tMAJOR\tPINNED")
+	for _, o := range reports {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", o.Tool, o.Current, orDash(o.Patch), orDash(o.Minor), orDash(o.Major), orDash(o.Pinned))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	cmd.Println("\nRun `vrsr upgrade [tool] --level patch|minor|major` to upgrade.")
+	return nil
+}
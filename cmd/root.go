@@ -1,20 +1,28 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/cli/tools"
+	"github.com/stepbeta/vrsr/internal/shim"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var (
 	cfgFile string
+	// toolDefs holds the definitions of the registered tools, by name
+	toolDefs = map[string]tools.ToolDef{}
+	// cancelTimeout releases the context of the command set up by the "timeout" setting, if any
+	cancelTimeout context.CancelFunc = func() {}
 	// override at build time using `go build -ldflags "-X github.com/stepbeta/vrsr/cmd.Version=x.y.z"`
 	Version = "0.0.1"
 	rootCmd = &cobra.Command{
@@ -24,18 +32,45 @@ var (
 		// PersistentPreRunE is called after flags are parsed but before the
 		// command's RunE function is called.
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return initializeConfig(cmd)
+			if err := initializeConfig(cmd); err != nil {
+				return err
+			}
+			if timeout := viper.GetDuration("timeout"); timeout > 0 {
+				var ctx context.Context
+				ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+				cmd.SetContext(ctx)
+			}
+			return nil
 		},
 	}
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// SIGINT and SIGTERM cancel the context of the command, so that it can clean up
+// its partial downloads before exiting; a second signal kills vrsr right away.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	registerToolCommands()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	switch {
+	case err == nil:
+		return
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		os.Exit(130)
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "%v (the command took longer than --timeout %s)\n", err, viper.GetDuration("timeout"))
+	default:
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
 	}
+	os.Exit(1)
 }
 
 func init() {
@@ -56,12 +91,48 @@ func init() {
 		os.Exit(1)
 	}
 	rootCmd.PersistentFlags().StringP("vrs-path", "d", defaultVrsPath, "Absolute path to folder storing downloaded tools binary versions")
+	// activation mode
+	rootCmd.PersistentFlags().String("mode", shim.ModeSymlink, fmt.Sprintf("How the active versions are exposed in bin-path: %q or %q", shim.ModeSymlink, shim.ModeShim))
+	// offline mode
+	rootCmd.PersistentFlags().Bool("offline", false, "Never access the network, only use the releases cache and the installed versions")
+	// timeouts
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Give up on the command after this long, e.g. \"5m\" (0 for no limit)")
+	rootCmd.PersistentFlags().Duration("connect-timeout", utils.DefaultConnectTimeout, "Give up connecting to a server after this long")
+	rootCmd.PersistentFlags().Duration("idle-timeout", utils.DefaultIdleTimeout, "Give up on a download receiving nothing for this long")
+}
 
-	// commands
-	rootCmd.AddCommand(tools.NewKindCommand())
-	rootCmd.AddCommand(tools.NewTalosctlCommand())
-	rootCmd.AddCommand(tools.NewKubectlCommand())
-	rootCmd.AddCommand(tools.NewHelmCommand())
+// registerToolCommands adds a subcommand for each built-in or declared tool.
+// This must happen before cobra parses the arguments, so the config file is
+// looked up straight from os.Args.
+func registerToolCommands() {
+	defs, warnings := tools.LoadToolDefs(configFileFromArgs(os.Args[1:]))
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "Warning: skipping tool definition:", w)
+	}
+	for _, td := range defs {
+		if c, _, err := rootCmd.Find([]string{td.Name}); err == nil && c != rootCmd {
+			fmt.Fprintf(os.Stderr, "Warning: tool %q conflicts with an existing command, skipping it\n", td.Name)
+			continue
+		}
+		rootCmd.AddCommand(tools.NewToolCommand(td))
+		toolDefs[td.Name] = td
+	}
+}
+
+// configFileFromArgs returns the value of the --config flag, if any.
+func configFileFromArgs(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(a, "--config="); ok {
+			return v
+		}
+		if a == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
 }
 
 // initializeConfig sets up Viper to read in config files and environment variables
@@ -73,20 +144,8 @@ func initializeConfig(cmd *cobra.Command) error {
 	viper.AutomaticEnv()
 
 	// 2. Handle the configuration file.
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Search for a config file in default locations.
-		home, err := os.UserHomeDir()
-		// Only panic if we can't get the home directory.
-		cobra.CheckErr(err)
-
-		// Search for a config file with the name "config" (without extension).
-		viper.AddConfigPath(".")
-		viper.AddConfigPath(home + "/.vrsr")
-		viper.SetConfigName("config")
-		viper.SetConfigType("yaml")
+	if err := utils.SetConfigSource(viper.GetViper(), cfgFile); err != nil {
+		return err
 	}
 
 	// 3. Read the configuration file.
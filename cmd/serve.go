@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/mirror"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the installed versions and the releases cache to other machines",
+	Long: "Serves over HTTP the releases cache and the versions installed into the vrs-path, for machines whose " +
+		"`mirror` setting points at this server (e.g. `mirror: http://ci-cache:8080`). Their `install` and `list-remote` " +
+		"then pull from it instead of upstream, falling back to upstream for what the mirror does not serve.\n\n" +
+		"Only binaries for the platform of this machine are served. The layout is:\n\n" +
+		"  GET /v1/tools                                              index of the tools, their cached releases and installed versions\n" +
+		"  GET /v1/tools/<tool>/releases.json                         releases cache of the tool\n" +
+		"  GET /v1/tools/<tool>/versions/<tag>/<os>/<arch>/manifest.json  install manifest, with the SHA-256 of the binary and of the upstream artifact\n" +
+		"  GET /v1/tools/<tool>/versions/<tag>/<os>/<arch>/binary         the binary",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve(cmd)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	if err := viper.BindPFlag("serve.addr", serveCmd.Flags().Lookup("addr")); err != nil {
+		serveCmd.PrintErr(err)
+		panic(err)
+	}
+	rootCmd.AddCommand(serveCmd)
+}
+
+// serve runs the mirror server until it fails or the command is interrupted
+func serve(cmd *cobra.Command) error {
+	vrsPath := viper.GetString("vrs-path")
+	tools := make([]string, 0, len(toolDefs))
+	for tool := range toolDefs {
+		tools = append(tools, tool)
+	}
+	slices.Sort(tools)
+	handler := mirror.NewHandler(vrsPath, tools)
+
+	addr := viper.GetString("serve.addr")
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			handler.ServeHTTP(rec, r)
+			cmd.PrintErrf("%s %s %s %d\n", r.RemoteAddr, r.Method, r.URL.Path, rec.status)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	stop := context.AfterFunc(cmd.Context(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	defer stop()
+	cmd.Printf("Serving %s and the releases cache on %s\n", vrsPath, addr)
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return context.Cause(cmd.Context())
+	}
+	return fmt.Errorf("mirror server stopped: %w", err)
+}
+
+// statusRecorder records the status code of a response, for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/project"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of every managed tool",
+	Long: fmt.Sprintf("Shows, for every tool, the active version, how many versions are installed and their disk usage, "+
+		"the newest upstream version known by the releases cache and whether it is an update.\n\n"+
+		"Tools whose active version differs from the one pinned by the nearest %s are flagged as drifted: run `vrsr sync` to fix them.\n\n"+
+		"No network access is done, run `vrsr <tool> list-remote -f` to refresh the upstream versions.", project.FileName),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return status(cmd)
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringP("output", "o", common.OutputText, fmt.Sprintf("Output format: %s, %s or %s", common.OutputText, common.OutputJSON, common.OutputYAML))
+	if err := viper.BindPFlag("status.output", statusCmd.Flags().Lookup("output")); err != nil {
+		statusCmd.PrintErr(err)
+		panic(err)
+	}
+	rootCmd.AddCommand(statusCmd)
+}
+
+// status prints the status of every registered tool
+func status(cmd *cobra.Command) error {
+	format, err := common.ValidateOutputFormat(viper.GetString("status.output"))
+	if err != nil {
+		return err
+	}
+	statuses := []common.ToolStatus{}
+	for _, c := range rootCmd.Commands() {
+		if _, ok := toolDefs[c.Name()]; !ok {
+			continue
+		}
+		st, err := common.GetToolStatus(c.Name())
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Name(), err)
+		}
+		statuses = append(statuses, st)
+	}
+	if format != common.OutputText {
+		return common.PrintOutput(cmd.OutOrStdout(), format, statuses)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TOOL\tACTIVE\tINSTALLED\tDISK\tLATEST\tPINNED")
+	var drifted []common.ToolStatus
+	for _, st := range statuses {
+		latest := orDash(st.Latest)
+		if st.UpdateAvailable {
+			latest += " (update)"
+		}
+		pinned := orDash(st.Pinned)
+		if st.Drift {
+			pinned += " (DRIFT)"
+			drifted = append(drifted, st)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", st.Tool, orDash(st.Active), st.Installed, humanize.Bytes(uint64(st.DiskUsage)), latest, pinned)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, st := range drifted {
+		if st.PinnedResolved == "" {
+			cmd.Printf("\nWarning: %s pins %s %s, which is not installed.", st.ProjectFile, st.Tool, st.Pinned)
+		} else {
+			cmd.Printf("\nWarning: %s pins %s %s, but %s is active.", st.ProjectFile, st.Tool, st.PinnedResolved, orDash(st.Active))
+		}
+	}
+	if len(drifted) > 0 {
+		cmd.Println("\nRun `vrsr sync` to install and activate the pinned versions.")
+	}
+	return nil
+}
+
+// orDash returns s, or a dash when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
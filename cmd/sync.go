@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/shim"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Install and activate the tool versions pinned by the project",
+	Long: fmt.Sprintf("Looks for the nearest %s file, walking up from the current directory, "+
+		"then installs any missing pinned version and activates it.\n\n"+
+		"When a %s is present, the locked tags are installed and verified against it.\n\n"+
+		"In shim mode the pinned versions are only installed, as the shims select them automatically.", project.FileName, project.LockFileName),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncProject(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
+
+// syncProject installs and uses every tool version pinned by the project file
+func syncProject(cmd *cobra.Command) error {
+	f, err := project.FindFromWd()
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return fmt.Errorf("no %s file found in the current directory or any parent", project.FileName)
+	}
+	cmd.Printf("Using project file %s\n", f.Path)
+	lock, err := project.LoadLock(project.LockPath(f.Path))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tool := range f.ToolNames() {
+		vrs, _ := f.Version(tool)
+		if lock != nil {
+			lt, ok := lock.Tool(tool)
+			if !ok {
+				cmd.Printf("Warning: %s is not locked, run `vrsr lock update` to lock it\n", tool)
+			} else if lt.Version != vrs {
+				errs = append(errs, fmt.Errorf("%s: %s is out of date (pinned %s, locked %s): run `vrsr lock update`", tool, lock.Path, vrs, lt.Version))
+				continue
+			} else {
+				vrs = lt.Tag
+			}
+		}
+		td, ok := toolDefs[tool]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown tool %q", tool))
+			continue
+		}
+		skipVerify := viper.GetBool(tool + ".install.skip-verify")
+		if viper.GetString("mode") == shim.ModeShim {
+			// shims pick the pinned version by themselves, it only needs to be installed
+			if err := syncShim(cmd, tool, vrs, td.RepoConf(), skipVerify); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", tool, vrs, err))
+			}
+			continue
+		}
+		if utils.IsToolInUse(tool, vrs) {
+			cmd.Printf("%s version %s is already in use\n", tool, vrs)
+			continue
+		}
+		// install on the fly any missing version
+		if err := common.UseVersion(cmd, vrs, tool, td.RepoConf(), common.UseOptions{Install: true, SkipVerify: skipVerify}); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", tool, vrs, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncShim installs the pinned version if no installed version matches it and makes sure the tool has a shim
+func syncShim(cmd *cobra.Command, tool, vrs string, repoConf github.RepoConfDef, skipVerify bool) error {
+	_, err := utils.ResolveInstalled(viper.GetString("vrs-path"), tool, vrs)
+	if errors.Is(err, utils.ErrNoMatchingVersion) {
+		if _, err := common.InstallVersion(cmd, vrs, tool, repoConf, common.InstallOptions{SkipVerify: skipVerify, Quiet: true}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	binPath := viper.GetString("bin-path")
+	if utils.IsShim(filepath.Join(binPath, tool)) {
+		return nil
+	}
+	return shim.Install(binPath, viper.GetString("vrs-path"), tool)
+}
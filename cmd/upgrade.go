@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+var upgradeLevels = []string{utils.LevelPatch, utils.LevelMinor, utils.LevelMajor}
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [tool]",
+	Short: "Install and use the newest release of the tools in use",
+	Long: fmt.Sprintf("Upgrades the active version of the given tool, or of every tool in use, to the newest release "+
+		"allowed by --level: \"patch\" stays on the same minor series, \"minor\" on the same major version, \"major\" takes any newer release.\n\n"+
+		"The new version is installed when missing and made the active one. "+
+		"Tools pinned by the nearest %s are only upgraded within the pinned version, and tools locked by its %s "+
+		"not past the locked version: run `vrsr lock update` first to move the lock.", project.FileName, project.LockFileName),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return upgrade(cmd, args)
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().String("level", utils.LevelPatch, fmt.Sprintf("Upgrade level: %s, %s or %s", utils.LevelPatch, utils.LevelMinor, utils.LevelMajor))
+	if err := viper.BindPFlag("upgrade.level", upgradeCmd.Flags().Lookup("level")); err != nil {
+		upgradeCmd.PrintErr(err)
+		panic(err)
+	}
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+// upgrade upgrades the given tool, or every tool in use, at the configured level
+func upgrade(cmd *cobra.Command, args []string) error {
+	level := viper.GetString("upgrade.level")
+	if !slices.Contains(upgradeLevels, level) {
+		return fmt.Errorf("unsupported upgrade level %q, use %s, %s or %s", level, utils.LevelPatch, utils.LevelMinor, utils.LevelMajor)
+	}
+	var names []string
+	if len(args) == 1 {
+		if _, ok := toolDefs[args[0]]; !ok {
+			return fmt.Errorf("unknown tool %q", args[0])
+		}
+		names = args
+	} else {
+		for _, c := range rootCmd.Commands() {
+			if _, ok := toolDefs[c.Name()]; ok {
+				names = append(names, c.Name())
+			}
+		}
+	}
+
+	var errs []error
+	for _, tool := range names {
+		current, target, err := common.UpgradeTarget(cmd.Context(), tool, toolDefs[tool].RepoConf(), level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tool, err))
+			continue
+		}
+		if current == "" {
+			if len(args) == 1 {
+				errs = append(errs, fmt.Errorf("no %s version in use: run `vrsr %s use <version>` first", tool, tool))
+			}
+			continue
+		}
+		if target == "" {
+			msg := fmt.Sprintf("%s %s is up to date (%s level)", tool, current, level)
+			if pinned, projectFile := project.PinnedVersion(tool); pinned != "" {
+				msg += fmt.Sprintf(", within %s pinned by %s", pinned, projectFile)
+			}
+			if lock, err := project.FindLock(); err == nil {
+				if lt, ok := lock.Tool(tool); ok {
+					msg += fmt.Sprintf(", up to %s locked by %s (run `vrsr lock update` to allow newer versions)", lt.Tag, lock.Path)
+				}
+			}
+			cmd.Println(msg)
+			continue
+		}
+		cmd.Printf("Upgrading %s from %s to %s\n", tool, current, target)
+		// install on the fly, then switch to the new version: failing to switch fails the upgrade
+		repoConf := toolDefs[tool].RepoConf()
+		opts := common.InstallOptions{SkipVerify: viper.GetBool(tool + ".install.skip-verify"), Quiet: true}
+		if _, err := common.InstallVersion(cmd, target, tool, repoConf, opts); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", tool, target, err))
+			continue
+		}
+		if err := common.UseVersion(cmd, target, tool, repoConf, common.UseOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s is installed but could not be switched to: %w", tool, target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
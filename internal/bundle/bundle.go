@@ -0,0 +1,275 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// FormatVersion is the version of the bundle layout written by Writer.
+const FormatVersion = 1
+
+// manifestName is the name of the bundle index inside the tarball.
+const manifestName = "bundle.json"
+
+// Manifest is the index of a bundle. A bundle is a tarball holding, besides the manifest,
+// the raw artifacts under "artifacts/" and the releases caches under "cache/".
+type Manifest struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	Artifacts []Artifact `json:"artifacts"`
+	// Caches maps tool names to the file holding their releases cache.
+	Caches map[string]string `json:"caches,omitempty"`
+}
+
+// Artifact is a tool version for a platform, stored in the bundle as downloaded upstream.
+type Artifact struct {
+	Tool     string `json:"tool"`
+	Tag      string `json:"tag"`
+	Platform string `json:"platform"`
+	// File is the path of the artifact inside the bundle.
+	File      string `json:"file"`
+	SourceURL string `json:"source_url"`
+	SHA256    string `json:"sha256"`
+	// Verified tells whether SHA256 was checked against a published or locked checksum when
+	// the bundle was created, rather than only computed from the download.
+	Verified bool `json:"verified"`
+	// ArchiveType and BinaryPath tell how to extract the binary from the artifact.
+	ArchiveType string `json:"archive_type,omitempty"`
+	BinaryPath  string `json:"binary_path,omitempty"`
+}
+
+// ArtifactFile returns the path inside the bundle of the artifact of the tool version for the platform.
+func ArtifactFile(tool, tag, platform string) string {
+	return path.Join("artifacts", tool, tag, strings.ReplaceAll(platform, "/", "-"), tool)
+}
+
+// CacheFile returns the path inside the bundle of the releases cache of the tool.
+func CacheFile(tool string) string {
+	return path.Join("cache", tool+"-releases.json")
+}
+
+// Writer writes a bundle.
+type Writer struct {
+	tw *tar.Writer
+	m  Manifest
+}
+
+// NewWriter returns a Writer writing the bundle to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		tw: tar.NewWriter(w),
+		m:  Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC(), Caches: map[string]string{}},
+	}
+}
+
+// AddArtifact adds the artifact, whose content is read from f.
+func (b *Writer) AddArtifact(a Artifact, f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	a.File = ArtifactFile(a.Tool, a.Tag, a.Platform)
+	if err := b.add(a.File, fi.Size(), f); err != nil {
+		return err
+	}
+	b.m.Artifacts = append(b.m.Artifacts, a)
+	return nil
+}
+
+// AddCache adds the releases cache of the tool.
+func (b *Writer) AddCache(tool string, content []byte) error {
+	name := CacheFile(tool)
+	if err := b.add(name, int64(len(content)), bytes.NewReader(content)); err != nil {
+		return err
+	}
+	b.m.Caches[tool] = name
+	return nil
+}
+
+// Close writes the manifest and finishes the bundle.
+func (b *Writer) Close() error {
+	content, err := json.MarshalIndent(b.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := b.add(manifestName, int64(len(content)), bytes.NewReader(content)); err != nil {
+		return err
+	}
+	return b.tw.Close()
+}
+
+// add writes a file entry to the tarball.
+func (b *Writer) add(name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: b.m.CreatedAt,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(b.tw, r)
+	return err
+}
+
+// Extract unpacks the bundle read from r into dir and returns its manifest.
+func Extract(r io.Reader, dir string) (Manifest, error) {
+	var m Manifest
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return m, fmt.Errorf("invalid bundle: unsafe path %q", hdr.Name)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return m, err
+		}
+		f, err := os.Create(dest)
+		if err != nil {
+			return m, err
+		}
+		_, err = io.Copy(f, tr)
+		if err1 := f.Close(); err == nil && err1 != nil {
+			err = err1
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, fmt.Errorf("invalid bundle: missing %s", manifestName)
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return m, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Version > FormatVersion {
+		return m, fmt.Errorf("bundle format version %d is not supported, upgrade vrsr", m.Version)
+	}
+	return m, nil
+}
+
+// Install stores the artifacts of the extracted bundle in dir for the given platform into vrsPath,
+// verifying their checksums, and returns the ones installed. Versions already installed are skipped.
+// Waits for other vrsr processes installing the same tools are reported to w and abandoned when ctx is done.
+func (m Manifest) Install(ctx context.Context, w io.Writer, dir, vrsPath, platform string) ([]Artifact, error) {
+	var installed []Artifact
+	for _, a := range m.Artifacts {
+		if a.Platform != platform {
+			continue
+		}
+		ok, err := installArtifact(ctx, w, dir, vrsPath, a)
+		if err != nil {
+			return installed, fmt.Errorf("%s %s: %w", a.Tool, a.Tag, err)
+		}
+		if ok {
+			installed = append(installed, a)
+		}
+	}
+	return installed, nil
+}
+
+// installArtifact extracts the binary of the artifact into vrsPath, unless it is installed already,
+// and reports whether it did
+func installArtifact(ctx context.Context, w io.Writer, dir, vrsPath string, a Artifact) (bool, error) {
+	unlock, err := utils.LockInstall(ctx, w, vrsPath, a.Tool)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if p, err := utils.GetBinaryPath(vrsPath, a.Tool, a.Tag); err == nil {
+		if _, err := os.Stat(p); err == nil {
+			return false, nil
+		}
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(path.Clean(a.File))))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if !a.Verified {
+		_, _ = fmt.Fprintf(w, "Warning: the checksum of %s %s for %s was not verified upstream when the bundle was created\n", a.Tool, a.Tag, a.Platform)
+	}
+	return true, utils.SaveBinary(f, vrsPath, utils.Artifact{
+		Tool:      a.Tool,
+		Version:   a.Tag,
+		SourceURL: a.SourceURL,
+		Archive:   utils.ArchiveSpec{Type: a.ArchiveType, BinaryPath: a.BinaryPath},
+		Checksum:  a.SHA256,
+	})
+}
+
+// RestoreCaches writes the releases caches of the extracted bundle in dir, unless the local
+// cache is more recent, and returns the tools whose cache was restored. Waits for other vrsr
+// processes writing the same caches are reported to w and abandoned when ctx is done.
+func (m Manifest) RestoreCaches(ctx context.Context, w io.Writer, dir string) ([]string, error) {
+	var restored []string
+	for tool, file := range m.Caches {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path.Clean(file))))
+		if err != nil {
+			return restored, err
+		}
+		var data utils.ReleasesData
+		if err := json.Unmarshal(content, &data); err != nil {
+			return restored, fmt.Errorf("invalid releases cache of %s: %w", tool, err)
+		}
+		if data.Version > utils.CacheVersion {
+			return restored, fmt.Errorf("releases cache version %d of %s is not supported, upgrade vrsr", data.Version, tool)
+		}
+		ok, err := restoreCache(ctx, w, tool, data)
+		if err != nil {
+			return restored, err
+		}
+		if ok {
+			restored = append(restored, tool)
+		}
+	}
+	sort.Strings(restored)
+	return restored, nil
+}
+
+// restoreCache writes the releases cache of the tool, unless the local one is more recent,
+// and reports whether it did.
+func restoreCache(ctx context.Context, w io.Writer, tool string, data utils.ReleasesData) (bool, error) {
+	unlock, err := utils.LockCache(ctx, w, tool)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	local, err := utils.ReadFromCache(tool, 0)
+	if err == nil && len(local.Releases) > 0 && !local.Timestamp.Before(data.Timestamp) {
+		return false, nil
+	}
+	return true, utils.WriteCache(tool, data)
+}
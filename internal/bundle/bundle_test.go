@@ -0,0 +1,165 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestBundle_RoundTrip(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+
+	artifactPath := filepath.Join(td, "artifact")
+	if err := os.WriteFile(artifactPath, []byte("ok"), 0o644); err != nil {
+		t.Fatalf("failed to write artifact: %v", err)
+	}
+	cache, err := json.Marshal(utils.ReleasesData{
+		Timestamp: time.Now().UTC(),
+		Version:   utils.CacheVersion,
+		Releases:  []utils.Release{{Tag: "v1.0.0"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal cache: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, platform := range []string{"linux/amd64", "darwin/arm64"} {
+		f, err := os.Open(artifactPath)
+		if err != nil {
+			t.Fatalf("failed to open artifact: %v", err)
+		}
+		err = w.AddArtifact(Artifact{
+			Tool:     "bundletool",
+			Tag:      "v1.0.0",
+			Platform: platform,
+			// sha256("ok")
+			SHA256:   "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df",
+			Verified: platform == "linux/amd64",
+		}, f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("failed to add artifact: %v", err)
+		}
+	}
+	if err := w.AddCache("bundletool", cache); err != nil {
+		t.Fatalf("failed to add cache: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close bundle: %v", err)
+	}
+
+	dir := filepath.Join(td, "extracted")
+	m, err := Extract(&buf, dir)
+	if err != nil {
+		t.Fatalf("failed to extract bundle: %v", err)
+	}
+	if len(m.Artifacts) != 2 || m.Caches["bundletool"] != CacheFile("bundletool") {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+
+	var warnings strings.Builder
+	installed, err := m.Install(context.Background(), &warnings, dir, vrsPath, "linux/amd64")
+	if err != nil {
+		t.Fatalf("failed to install bundle: %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Fatalf("expected no warning for a verified artifact, got: %s", warnings.String())
+	}
+	if len(installed) != 1 || installed[0].Platform != "linux/amd64" {
+		t.Fatalf("expected only the linux/amd64 artifact to be installed, got: %+v", installed)
+	}
+	content, err := os.ReadFile(filepath.Join(vrsPath, "bundletool", "bundletool-v1.0.0"))
+	if err != nil || string(content) != "ok" {
+		t.Fatalf("expected the binary to be installed, got %q (%v)", content, err)
+	}
+	// versions already installed are skipped
+	if installed, err := m.Install(context.Background(), io.Discard, dir, vrsPath, "linux/amd64"); err != nil || len(installed) != 0 {
+		t.Fatalf("expected nothing to be installed twice, got %+v (%v)", installed, err)
+	}
+
+	restored, err := m.RestoreCaches(context.Background(), io.Discard, dir)
+	if err != nil || len(restored) != 1 {
+		t.Fatalf("expected the cache to be restored, got %v (%v)", restored, err)
+	}
+	data, err := utils.ReadFromCache("bundletool", 0)
+	if err != nil || len(data.Releases) != 1 || data.Releases[0].Tag != "v1.0.0" {
+		t.Fatalf("unexpected restored cache: %+v (%v)", data, err)
+	}
+	// the local cache is as recent as the bundled one
+	if restored, err := m.RestoreCaches(context.Background(), io.Discard, dir); err != nil || len(restored) != 0 {
+		t.Fatalf("expected the local cache to be kept, got %v (%v)", restored, err)
+	}
+}
+
+func TestBundle_ChecksumMismatch(t *testing.T) {
+	td := t.TempDir()
+	f, err := os.CreateTemp(td, "artifact")
+	if err != nil {
+		t.Fatalf("failed to create artifact: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := f.WriteString("tampered"); err != nil {
+		t.Fatalf("failed to write artifact: %v", err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatalf("failed to rewind artifact: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.AddArtifact(Artifact{Tool: "bundletool", Tag: "v1.0.0", Platform: "linux/amd64", SHA256: strings.Repeat("0", 64)}, f); err != nil {
+		t.Fatalf("failed to add artifact: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close bundle: %v", err)
+	}
+	dir := filepath.Join(td, "extracted")
+	m, err := Extract(&buf, dir)
+	if err != nil {
+		t.Fatalf("failed to extract bundle: %v", err)
+	}
+	vrsPath := filepath.Join(td, "versions")
+	if _, err := m.Install(context.Background(), io.Discard, dir, vrsPath, "linux/amd64"); err == nil {
+		t.Fatalf("expected checksum mismatch error")
+	}
+	if _, err := os.Stat(filepath.Join(vrsPath, "bundletool", "bundletool-v1.0.0")); err == nil {
+		t.Fatalf("expected the tampered binary not to be installed")
+	}
+}
+
+func TestExtract_RejectsUnsafePaths(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("evil")
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("failed to write content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+
+	td := t.TempDir()
+	if _, err := Extract(&buf, filepath.Join(td, "extracted")); err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Fatalf("expected unsafe path error, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(td, "evil")); err == nil {
+		t.Fatalf("expected nothing to be written outside the bundle dir")
+	}
+}
@@ -1,22 +1,40 @@
 package common
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 // InitCommand initializes the common commands for the specified tool
 func InitCommand(cmd *cobra.Command, tool string, repoConf github.RepoConfDef) {
+	// tools released from the same repository share the releases cache
+	utils.RegisterReleasesSource(tool, repoConf.Source)
 	// list
 	cmd.AddCommand(newListCommand(tool))
+	// current
+	cmd.AddCommand(newCurrentCommand(tool))
 	// list-remote
 	cmd.AddCommand(newGithubListRemoteCommand(tool, repoConf))
+	// notes
+	cmd.AddCommand(newNotesCommand(tool, repoConf))
 	// install
-	installType := InstallGitHubCmd
-	if repoConf.DownloadURL != "" {
-		installType = InstallDownloadCmd
-	}
-	cmd.AddCommand(newInstallCommand(tool, repoConf, installType))
+	cmd.AddCommand(newInstallCommand(tool, repoConf, installTypeOf(repoConf)))
 	// use
-	cmd.AddCommand(newUseCommand(tool))
+	cmd.AddCommand(newUseCommand(tool, repoConf))
+	// uninstall
+	cmd.AddCommand(newUninstallCommand(tool))
+	// prune
+	cmd.AddCommand(newPruneCommand(tool))
+}
+
+// commandContext returns the context of cmd, cancelled on interrupt or timeout,
+// or the background one when cmd is not run by cobra (e.g. in tests).
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
 }
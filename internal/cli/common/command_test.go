@@ -2,9 +2,12 @@ package common
 
 import (
 	"testing"
+	"time"
 
+	gh "github.com/google/go-github/v78/github"
 	"github.com/spf13/cobra"
 	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 // helper to find a subcommand by use string
@@ -17,6 +20,18 @@ func findSubcmd(root *cobra.Command, use string) *cobra.Command {
 	return nil
 }
 
+// cacheReleases stores the releases of the tool in its cache, as a complete fetch does.
+func cacheReleases(t *testing.T, tool string, rels []*gh.RepositoryRelease) {
+	t.Helper()
+	releases, notes := utils.CompactReleases(rels)
+	if err := utils.WriteCache(tool, utils.ReleasesData{Timestamp: time.Now().UTC(), Releases: releases, Complete: true}); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	if err := utils.SaveReleaseNotes(tool, notes); err != nil {
+		t.Fatalf("failed to save release notes: %v", err)
+	}
+}
+
 func TestInitCommand_RegistersCommonSubcommands(t *testing.T) {
 	root := &cobra.Command{Use: "root"}
 	repo := github.RepoConfDef{}
@@ -35,4 +50,7 @@ func TestInitCommand_RegistersCommonSubcommands(t *testing.T) {
 	if findSubcmd(root, "install <version>") == nil {
 		t.Fatalf("install subcommand not registered")
 	}
+	if findSubcmd(root, "current") == nil {
+		t.Fatalf("current subcommand not registered")
+	}
 }
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/shim"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// newCurrentCommand creates a new 'current' command for the specified tool
+func newCurrentCommand(tool string) *cobra.Command {
+	currentCmd := &cobra.Command{
+		Use:   "current",
+		Short: fmt.Sprintf("Show the %s version in use", tool),
+		Long: fmt.Sprintf("Prints the %s version currently in use.\n\n"+
+			"In shim mode this is the version the shim would run from the current directory, "+
+			"selected by %s, the project file or the global version.", tool, shim.EnvVar(tool)),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return current(cmd, tool)
+		},
+	}
+	addOutputFlag(currentCmd, tool)
+	return currentCmd
+}
+
+// current prints the version of the tool in use
+func current(cmd *cobra.Command, tool string) error {
+	format, err := getOutputFormat(cmd, tool)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if format != OutputText {
+		out = redirectMessages(cmd)
+	}
+	vrs, source, err := currentVersion(tool)
+	if err != nil {
+		cmd.Println("Error getting current version:", err)
+		return err
+	}
+
+	if format != OutputText {
+		o := VersionOutput{Tool: tool}
+		if vrs != "" {
+			o = newVersionOutput(tool, vrs, source)
+			o.InUse = o.Installed
+		}
+		return PrintOutput(out, format, o)
+	}
+	if vrs == "" {
+		cmd.Printf("No %s version in use. Run `vrsr %s use <version>` to select one\n", tool, tool)
+		return nil
+	}
+	if source != shim.SourceGlobal {
+		cmd.Printf("%s (selected by %s)\n", vrs, source)
+		return nil
+	}
+	cmd.Println(vrs)
+	return nil
+}
+
+// currentVersion returns the version of the tool in use and what selected it
+func currentVersion(tool string) (string, string, error) {
+	binPath := viper.GetString("bin-path")
+	if viper.GetString("mode") != shim.ModeShim {
+		vrs, err := utils.GetVrsInUse(binPath, tool)
+		return vrs, shim.SourceGlobal, err
+	}
+	vrs, source, err := shim.Resolve(binPath, tool)
+	if errors.Is(err, shim.ErrNoVersion) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if source != shim.SourceGlobal {
+		// project files and the environment may hold partial versions or constraints
+		if resolved, err := utils.ResolveInstalled(viper.GetString("vrs-path"), tool, vrs); err == nil {
+			vrs = resolved
+		}
+	}
+	return vrs, source, nil
+}
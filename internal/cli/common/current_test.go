@@ -0,0 +1,70 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/shim"
+)
+
+func TestCurrent(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "currtool"
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	viper.Set("mode", shim.ModeSymlink)
+	viper.Set(tool+".current.output", OutputText)
+
+	cmd := &cobra.Command{Use: "current"}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	if err := current(cmd, tool); err != nil {
+		t.Fatalf("current returned error: %v", err)
+	}
+	if !strings.Contains(sb.String(), "No currtool version in use") {
+		t.Fatalf("unexpected output: %s", sb.String())
+	}
+
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	installed := filepath.Join(vrsPath, tool, tool+"-v1.2.3-rc.1")
+	if err := os.WriteFile(installed, []byte("x"), 0o755); err != nil {
+		t.Fatalf("failed to write binary: %v", err)
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(installed, filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	sb.Reset()
+	if err := current(cmd, tool); err != nil {
+		t.Fatalf("current returned error: %v", err)
+	}
+	if strings.TrimSpace(sb.String()) != "v1.2.3-rc.1" {
+		t.Fatalf("unexpected output: %q", sb.String())
+	}
+
+	viper.Set(tool+".current.output", OutputJSON)
+	sb.Reset()
+	if err := current(cmd, tool); err != nil {
+		t.Fatalf("current returned error: %v", err)
+	}
+	var o VersionOutput
+	if err := json.Unmarshal([]byte(sb.String()), &o); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", sb.String(), err)
+	}
+	if o.Tool != tool || o.Version != "v1.2.3-rc.1" || !o.Installed || !o.InUse || !o.Prerelease || o.Source != shim.SourceGlobal {
+		t.Fatalf("unexpected output: %+v", o)
+	}
+}
@@ -1,15 +1,24 @@
 package common
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"runtime"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/mirror"
+	"github.com/stepbeta/vrsr/internal/project"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
-var useOnInstall bool
+var (
+	useOnInstall bool
+	skipVerify   bool
+)
 
 type InstallCmdType int
 
@@ -18,18 +27,46 @@ const (
 	InstallDownloadCmd
 )
 
+// installTypeOf returns how the tool released from repoConf is installed.
+func installTypeOf(repoConf github.RepoConfDef) InstallCmdType {
+	if repoConf.DownloadURL != "" {
+		return InstallDownloadCmd
+	}
+	return InstallGitHubCmd
+}
+
+// InstallOptions tunes InstallVersion as the flags of the install command do.
+type InstallOptions struct {
+	// Use switches to the version once installed, best effort.
+	Use bool
+	// SkipVerify skips the SHA-256 verification of the download (unsafe).
+	SkipVerify bool
+	// Quiet leaves out the hint to switch to the installed version.
+	Quiet bool
+}
+
+// InstallVersion installs the version of the tool released from repoConf as `vrsr <tool> install` does,
+// with the given options instead of the ones set for the tool, and returns the exact version it resolved to.
+func InstallVersion(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef, opts InstallOptions) (string, error) {
+	return installVersion(cmd, vrs, tool, repoConf, installTypeOf(repoConf), opts)
+}
+
 // newGithubInstallCommand creates a new 'install' command for the specified tool.
 func newInstallCommand(tool string, repoConf github.RepoConfDef, installType InstallCmdType) *cobra.Command {
 	installCmd := &cobra.Command{
 		Use:   "install <version>",
 		Short: fmt.Sprintf("Download and install %s for the current OS/ARCH", tool),
 		Long: fmt.Sprintf("Download the %s binary for the current OS/ARCH at the specified version.\n\n"+
+			"Besides exact versions, \"latest\" (or \"stable\", the newest version that is not a pre-release), "+
+			"\"prerelease\" (the newest version, pre-releases included), partial versions such as \"1.30\" (newest patch) "+
+			"and semver constraints such as \"~1.29.0\" or \">=3.14 <4\" are resolved against the known releases. "+
+			"Constraints only match pre-releases when they name one, e.g. \">=1.31.0-0\".\n\n"+
+			"When the project %s pins this version, the locked tag is installed and its SHA-256 must match the lockfile.\n\n"+
 			"This binary will be saved into the path specified by the \"bin-path\" flag. It will be named \"%s-$version\".\n\n"+
-			"Make sure to check the \"use <version>\" command after installing a new version", tool, tool),
+			"Make sure to check the \"use <version>\" command after installing a new version", tool, project.LockFileName, tool),
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			skipMsg := len(args) > 1 && args[1] == "true"
-			return install(cmd, args[0], tool, repoConf, installType, skipMsg)
+			return install(cmd, args[0], tool, repoConf, installType, false)
 		},
 	}
 	// Bind flags to Viper keys so config file / env / flags work together.
@@ -38,71 +75,252 @@ func newInstallCommand(tool string, repoConf github.RepoConfDef, installType Ins
 		installCmd.PrintErr(err)
 		panic(err)
 	}
+	installCmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Skip the SHA-256 checksum verification of the download (unsafe)")
+	if err := viper.BindPFlag(fmt.Sprintf("%s.install.skip-verify", tool), installCmd.Flags().Lookup("skip-verify")); err != nil {
+		installCmd.PrintErr(err)
+		panic(err)
+	}
+	addOutputFlag(installCmd, tool)
 	return installCmd
 }
 
-// install downloads and installs the specified version of the tool from GitHub releases
+// install downloads and installs the specified version of the tool, with the options set for it,
+// printing the installed version in the requested output format
 func install(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef, installType InstallCmdType, skipMsg bool) error {
+	format, err := getOutputFormat(cmd, tool)
+	if err != nil {
+		return err
+	}
+	useOnInstall = viper.GetBool(tool + ".install.use")
+	skipVerify = viper.GetBool(tool + ".install.skip-verify")
+	opts := InstallOptions{Use: useOnInstall, SkipVerify: skipVerify, Quiet: skipMsg}
+	if format == OutputText {
+		_, err := installVersion(cmd, vrs, tool, repoConf, installType, opts)
+		return err
+	}
+	out := redirectMessages(cmd)
+	vrs, err = installVersion(cmd, vrs, tool, repoConf, installType, opts)
+	if err != nil {
+		return err
+	}
+	return PrintOutput(out, format, newVersionOutput(tool, vrs, ""))
+}
+
+// installVersion downloads and installs the specified version of the tool from GitHub releases,
+// returning the exact version it resolved to
+func installVersion(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef, installType InstallCmdType, opts InstallOptions) (string, error) {
+	lock, err := project.FindLock()
+	if err != nil {
+		cmd.Println("Error reading lockfile:", err)
+		return "", err
+	}
+	if tag, ok := lock.Locked(tool, vrs); ok && tag != vrs {
+		cmd.Printf("Resolved %s to %s version %s (locked by %s)\n", vrs, tool, tag, lock.Path)
+		vrs = tag
+	}
+	vrs, err = ResolveRemoteVersion(cmd, vrs, tool, repoConf)
+	if err != nil {
+		return "", err
+	}
+	vrsPath := viper.GetString("vrs-path")
+	// another vrsr may be installing the tool, wait for it: it may be this very version
+	unlock, err := lockInstalled(cmd, vrsPath, tool)
+	if err != nil {
+		cmd.Println("Error locking vrs path:", err)
+		return "", err
+	}
+	defer unlock()
 	if utils.IsToolInUse(tool, vrs) {
 		cmd.Printf("%s version %s is already installed and in use. Nothing to do\n", tool, vrs)
-		return nil
+		return vrs, nil
 	}
-	useOnInstall = viper.GetBool(tool + ".install.use")
 	if utils.IsToolInstalled(tool, vrs) {
 		cmd.Printf("%s version %s is already installed.\n", tool, vrs)
-		if !useOnInstall {
-			if !skipMsg {
+		if !opts.Use {
+			if !opts.Quiet {
 				cmd.Printf("To switch to that version run `vrsr %s use %s`\n", tool, vrs)
 			}
-			return nil
+			return vrs, nil
 		}
-		if err := useOnInstallFn(cmd, vrs, tool); err != nil {
-			return err
+		if err := useOnInstallFn(cmd, vrs, tool, repoConf, opts); err != nil {
+			return "", err
 		}
+		return vrs, nil
 	}
 
-	vrsPath := viper.GetString("vrs-path")
+	if utils.IsOffline() {
+		return "", fmt.Errorf("%w: %s %s is not installed and cannot be downloaded, import it with `vrsr bundle import`", utils.ErrOffline, tool, vrs)
+	}
+
+	if opts.SkipVerify {
+		cmd.PrintErrln("WARNING: checksum verification is DISABLED (--skip-verify).")
+		cmd.PrintErrf("WARNING: the downloaded %s %s binary will NOT be checked for integrity!\n", tool, vrs)
+	}
+	lockedSum := ""
+	if !opts.SkipVerify {
+		lockedSum, err = lockedChecksum(lock, tool, vrs)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	mirrored := false
+	if base := mirror.URL(); base != "" {
+		err := mirror.Install(commandContext(cmd), base, tool, vrs, vrsPath, mirror.InstallOptions{Verify: !opts.SkipVerify, Checksum: lockedSum})
+		switch {
+		case err == nil:
+			mirrored = true
+		case errors.Is(err, mirror.ErrNotMirrored):
+			cmd.Printf("%s version %s is not available on the mirror, downloading it upstream\n", tool, vrs)
+		default:
+			return "", lockDrift(checksumHint(err), lock, lockedSum)
+		}
+	}
+	if !mirrored {
+		if err := downloadUpstream(commandContext(cmd), cmd.ErrOrStderr(), vrsPath, vrs, tool, repoConf, installType, lockedSum); err != nil {
+			return "", lockDrift(err, lock, lockedSum)
+		}
+	}
+	cmd.Printf("%s version %s successfully installed\n", tool, vrs)
+
+	if !opts.Use {
+		if !opts.Quiet {
+			cmd.Printf("To switch to that version run `vrsr %s use %s`\n", tool, vrs)
+		}
+		return vrs, nil
+	}
+	return vrs, useOnInstallFn(cmd, vrs, tool, repoConf, opts)
+}
+
+// lockInstalled takes the install lock of the tool (see utils.LockInstall) and adopts the binaries
+// installed before manifests existed, so that their manifests are written once and not while an
+// install writes its own. The returned function releases the lock.
+func lockInstalled(cmd *cobra.Command, vrsPath, tool string) (func(), error) {
+	unlock, err := utils.LockInstall(commandContext(cmd), cmd.ErrOrStderr(), vrsPath, tool)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.AdoptInstalled(vrsPath, tool); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// downloadUpstream downloads the tool version from where the tool is released into vrsPath,
+// verifying it against lockedSum when set, or against the published checksum unless verification is skipped.
+// The progress of the download is reported to progress.
+func downloadUpstream(ctx context.Context, progress io.Writer, vrsPath, vrs, tool string, repoConf github.RepoConfDef, installType InstallCmdType, lockedSum string) error {
 	// depending on the install type we use the appropriate install method
 	switch installType {
 	case InstallGitHubCmd:
-		ghc := github.New(nil)
-		if err := ghc.DownloadRelease(tool, vrs, vrsPath, repoConf); err != nil {
+		ghc, err := github.NewFor(repoConf)
+		if err != nil {
 			return err
 		}
+		return checksumHint(ghc.DownloadRelease(ctx, tool, vrs, vrsPath, repoConf, github.DownloadOptions{
+			Verify:   !skipVerify,
+			Checksum: lockedSum,
+			Progress: progress,
+		}))
 	case InstallDownloadCmd:
-		if err := utils.DownloadBinary(repoConf.DownloadURL, tool, vrs, vrsPath, repoConf.Zipped); err != nil {
+		data := github.NewTemplateData(tool, vrs)
+		dlURL, err := github.Render(repoConf.DownloadURL, data)
+		if err != nil {
 			return err
 		}
+		archive, err := repoConf.ArchiveSpec(data)
+		if err != nil {
+			return err
+		}
+		checksum := lockedSum
+		if checksum == "" && !skipVerify {
+			checksum, err = repoConf.DownloadChecksum(ctx, data, dlURL)
+			if err != nil {
+				return checksumHint(err)
+			}
+		}
+		return checksumHint(utils.DownloadBinary(ctx, progress, vrsPath, utils.Artifact{
+			Tool:      tool,
+			Version:   vrs,
+			SourceURL: dlURL,
+			Archive:   archive,
+			Checksum:  checksum,
+		}))
 	default:
 		return fmt.Errorf("unknown install type")
 	}
-	cmd.Printf("%s version %s successfully installed\n", tool, vrs)
+}
 
-	if !useOnInstall {
-		if !skipMsg {
-			cmd.Printf("To switch to that version run `vrsr %s use %s`\n", tool, vrs)
+// ResolveRemoteVersion resolves the requested version (alias, partial version or constraint)
+// against the releases of the tool, using the releases cache when available
+func ResolveRemoteVersion(cmd *cobra.Command, input, tool string, repoConf github.RepoConfDef) (string, error) {
+	exact := utils.IsExactVersion(input)
+	if exact {
+		// no need to look at the releases for a version already installed
+		if vrs, err := utils.ResolveInstalled(viper.GetString("vrs-path"), tool, input); err == nil {
+			return vrs, nil
 		}
-		return nil
 	}
-	return useOnInstallFn(cmd, vrs, tool)
+	ghc, err := github.NewFor(repoConf)
+	if err != nil {
+		return "", err
+	}
+	releasesData, err := ghc.FetchAllReleases(commandContext(cmd), tool, github.FetchOptions{
+		IncludeDevel: true,
+		RepoConf:     repoConf,
+	})
+	var vrs string
+	if err == nil {
+		vrs, err = utils.ResolveVersion(input, utils.SemverFromReleases(releasesData.Releases, true))
+	}
+	if err != nil {
+		if exact {
+			// the releases cache may be outdated, try the version as given
+			return input, nil
+		}
+		return "", err
+	}
+	if vrs != input {
+		cmd.Printf("Resolved %s to %s version %s\n", input, tool, vrs)
+	}
+	return vrs, nil
 }
 
-// useOnInstallFn attempts to use the installed version immediately
-func useOnInstallFn(cmd *cobra.Command, vrs, tool string) error {
-	pCmd := cmd.Parent()
-	if pCmd == nil {
-		// this is a sign of something wrong, so we return an error
-		return fmt.Errorf("internal error: install command has no parent")
-	}
-	uCmd, _, err := pCmd.Find([]string{"use"})
-	if err != nil || uCmd.Name() != "use" {
-		cmd.Println("could not find sibling 'use' command")
-		cmd.Println("Skipping action")
-		cmd.Printf("To switch to that version run `vrsr %s use %s`\n", tool, vrs)
-		// this is a best effort, if there's an error we just return
-		return nil
+// checksumHint adds a hint on how to bypass the verification to checksum errors
+func checksumHint(err error) error {
+	if errors.Is(err, utils.ErrChecksumNotFound) {
+		return fmt.Errorf("%w (use --skip-verify to install without verification)", err)
+	}
+	return err
+}
+
+// lockedChecksum returns the SHA-256 recorded by the lockfile for the tool version on the
+// current platform. It is empty when the lockfile does not lock that version.
+func lockedChecksum(lock *project.Lock, tool, vrs string) (string, error) {
+	lt, ok := lock.Tool(tool)
+	if !ok || lt.Tag != vrs {
+		return "", nil
+	}
+	platform := project.Platform(runtime.GOOS, runtime.GOARCH)
+	artifact, ok := lt.Platforms[platform]
+	if !ok {
+		return "", fmt.Errorf("%s does not record %s %s for %s: add the platform with `vrsr lock update --platform %s`", lock.Path, tool, vrs, platform, platform)
 	}
-	if err := uCmd.RunE(uCmd, []string{vrs}); err != nil {
+	return artifact.SHA256, nil
+}
+
+// lockDrift explains checksum mismatches against the lockfile
+func lockDrift(err error, lock *project.Lock, lockedSum string) error {
+	if lockedSum != "" && errors.Is(err, utils.ErrChecksumMismatch) {
+		return fmt.Errorf("%w: the download drifted from the artifact recorded in %s (run `vrsr lock update` to accept it)", err, lock.Path)
+	}
+	return err
+}
+
+// useOnInstallFn attempts to use the installed version immediately
+func useOnInstallFn(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef, opts InstallOptions) error {
+	if err := UseVersion(cmd, vrs, tool, repoConf, UseOptions{SkipVerify: opts.SkipVerify}); err != nil {
 		cmd.Println("Error executing use:", err)
 		cmd.Println("Skipping action")
 		cmd.Printf("To switch to that version run `vrsr %s use %s`\n", tool, vrs)
@@ -1,13 +1,25 @@
 package common
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	gh "github.com/google/go-github/v78/github"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/mirror"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 func TestInstallCommands_EarlyReturnWhenInUseOrInstalled(t *testing.T) {
@@ -56,3 +68,275 @@ func TestInstallCommands_EarlyReturnWhenInUseOrInstalled(t *testing.T) {
 		t.Fatalf("install Download expected nil error when tool installed, got: %v", err)
 	}
 }
+
+func TestResolveRemoteVersion_UsesReleasesCache(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	viper.Set("vrs-path", filepath.Join(td, "versions"))
+	tool := "remotetool"
+	var rels []*gh.RepositoryRelease
+	for _, v := range []string{"v3.13.0", "v3.14.2", "v3.15.1", "v4.0.0", "v4.1.0-beta.1"} {
+		rels = append(rels, &gh.RepositoryRelease{TagName: gh.Ptr(v)})
+	}
+	cacheReleases(t, tool, rels)
+
+	for input, want := range map[string]string{
+		">=3.14 <4":  "v3.15.1",
+		"3.14":       "v3.14.2",
+		"stable":     "v4.0.0",
+		"latest":     "v4.0.0",
+		"prerelease": "v4.1.0-beta.1",
+		">=4.1.0-0":  "v4.1.0-beta.1",
+		// exact versions not in the cache are used as given
+		"v5.0.0": "v5.0.0",
+	} {
+		cmd := &cobra.Command{}
+		var sb strings.Builder
+		cmd.SetOut(&sb)
+		got, err := ResolveRemoteVersion(cmd, input, tool, github.RepoConfDef{})
+		if err != nil {
+			t.Fatalf("resolving %q returned error: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("expected %q to resolve to %s, got %s", input, want, got)
+		}
+		if input != want && !strings.Contains(sb.String(), "Resolved "+input+" to "+tool+" version "+want) {
+			t.Fatalf("expected resolution to be printed, got: %s", sb.String())
+		}
+	}
+	if _, err := ResolveRemoteVersion(&cobra.Command{}, "~2.0", tool, github.RepoConfDef{}); err == nil {
+		t.Fatalf("expected error when no release matches")
+	}
+}
+
+func TestInstall_VerifiesAgainstLockfile(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	viper.Set("vrs-path", filepath.Join(td, "versions"))
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	tool := "locktool"
+	cacheReleases(t, tool, []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.0.0")}, {TagName: gh.Ptr("v1.0.1")}})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	repoConf := github.RepoConfDef{DownloadURL: srv.URL + "/{{.Tool}}-{{.Version}}"}
+
+	projectDir := filepath.Join(td, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, project.FileName), []byte("tools:\n  locktool: \"1.0\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	writeLock := func(sum string) {
+		l := &project.Lock{Path: filepath.Join(projectDir, project.LockFileName), Tools: map[string]project.LockedTool{
+			tool: {Version: "1.0", Tag: "v1.0.0", Platforms: map[string]project.LockedArtifact{
+				project.Platform(runtime.GOOS, runtime.GOARCH): {SHA256: sum},
+			}},
+		}}
+		if err := l.Save(); err != nil {
+			t.Fatalf("failed to write lockfile: %v", err)
+		}
+	}
+	t.Chdir(projectDir)
+
+	// the artifact changed upstream since it was locked
+	writeLock(strings.Repeat("0", 64))
+	err := install(&cobra.Command{}, "1.0", tool, repoConf, InstallDownloadCmd, true)
+	if !errors.Is(err, utils.ErrChecksumMismatch) || !strings.Contains(err.Error(), project.LockFileName) {
+		t.Fatalf("expected lockfile drift error, got: %v", err)
+	}
+
+	// sha256("ok"), the locked tag is installed rather than the newest 1.0.x
+	writeLock("2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df")
+	if err := install(&cobra.Command{}, "1.0", tool, repoConf, InstallDownloadCmd, true); err != nil {
+		t.Fatalf("expected install matching the lockfile to succeed, got: %v", err)
+	}
+	if !utils.IsToolInstalled(tool, "v1.0.0") || utils.IsToolInstalled(tool, "v1.0.1") {
+		t.Fatalf("expected the locked version v1.0.0 to be installed")
+	}
+}
+
+func TestInstall_OfflineMode(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	t.Chdir(td)
+	viper.Set("vrs-path", filepath.Join(td, "versions"))
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	viper.Set("offline", true)
+	defer viper.Set("offline", false)
+	tool := "offlinetool"
+
+	// no releases cache, exact versions are tried as given
+	err := install(&cobra.Command{}, "v1.0.0", tool, github.RepoConfDef{DownloadURL: "https://example.com/{{.Tool}}"}, InstallDownloadCmd, true)
+	if !errors.Is(err, utils.ErrOffline) || !strings.Contains(err.Error(), "not installed") {
+		t.Fatalf("expected offline error, got: %v", err)
+	}
+	// aliases need the releases cache
+	err = install(&cobra.Command{}, "latest", tool, github.RepoConfDef{}, InstallGitHubCmd, true)
+	if !errors.Is(err, utils.ErrOffline) {
+		t.Fatalf("expected offline error, got: %v", err)
+	}
+}
+
+func TestInstall_PullsFromMirror(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	t.Chdir(td)
+	tool := "mirroredtool"
+	cacheReleases(t, tool, []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.0.0")}, {TagName: gh.Ptr("v2.0.0")}})
+	// the mirror host has v1.0.0 installed
+	serverPath := filepath.Join(td, "server")
+	if err := utils.SaveBinary(strings.NewReader("ok"), serverPath, utils.Artifact{Tool: tool, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("failed to install binary on the mirror: %v", err)
+	}
+	mirrorSrv := httptest.NewServer(mirror.NewHandler(serverPath, []string{tool}))
+	defer mirrorSrv.Close()
+	upstreamHits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits++
+		_, _ = w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+	repoConf := github.RepoConfDef{DownloadURL: upstream.URL + "/{{.Tool}}-{{.Version}}"}
+
+	viper.Set("vrs-path", filepath.Join(td, "versions"))
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	viper.Set(tool+".install.skip-verify", true)
+	viper.Set("mirror", mirrorSrv.URL)
+	defer viper.Set("mirror", "")
+
+	if err := install(&cobra.Command{}, "v1.0.0", tool, repoConf, InstallDownloadCmd, true); err != nil {
+		t.Fatalf("install from mirror returned error: %v", err)
+	}
+	if upstreamHits != 0 {
+		t.Fatalf("expected the mirrored version not to be downloaded upstream")
+	}
+	// versions the mirror lacks are downloaded upstream
+	if err := install(&cobra.Command{}, "v2.0.0", tool, repoConf, InstallDownloadCmd, true); err != nil {
+		t.Fatalf("install falling back upstream returned error: %v", err)
+	}
+	if upstreamHits != 1 || !utils.IsToolInstalled(tool, "v1.0.0") || !utils.IsToolInstalled(tool, "v2.0.0") {
+		t.Fatalf("expected v1.0.0 from the mirror and v2.0.0 from upstream to be installed")
+	}
+}
+
+func TestInstall_InterruptedDownloadLeavesNoPartialFile(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	t.Chdir(td)
+	tool := "interruptedtool"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		// the download stalls until interrupted
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	repoConf := github.RepoConfDef{DownloadURL: srv.URL + "/{{.Tool}}-{{.Version}}"}
+	vrsPath := filepath.Join(td, "versions")
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	viper.Set(tool+".install.skip-verify", true)
+
+	// interrupt once the first bytes are saved
+	partPattern := filepath.Join(td, ".vrsr", "downloads", "*.part")
+	go func() {
+		defer cancel()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if partial, _ := filepath.Glob(partPattern); len(partial) == 1 {
+				if fi, err := os.Stat(partial[0]); err == nil && fi.Size() > 0 {
+					return
+				}
+			}
+		}
+	}()
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	err := install(cmd, "v1.0.0", tool, repoConf, InstallDownloadCmd, true)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the download to be cancelled, got %v", err)
+	}
+	if utils.IsToolInstalled(tool, "v1.0.0") {
+		t.Fatalf("expected the interrupted download not to be installed")
+	}
+	if partial, _ := filepath.Glob(filepath.Join(vrsPath, tool, tool+"-download-*")); len(partial) != 0 {
+		t.Fatalf("expected the partial download to be removed, found %v", partial)
+	}
+	// it is kept in the downloads cache instead, to be resumed
+	if partial, _ := filepath.Glob(partPattern); len(partial) != 1 {
+		t.Fatalf("expected the partial download to be kept in the downloads cache, found %v", partial)
+	}
+}
+
+func TestInstall_ResumesInterruptedDownload(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	t.Chdir(td)
+	tool := "resumedtool"
+	content, etag := strings.Repeat("v1", 512), `"v1"`
+	cut := true
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		if cut {
+			// the connection drops halfway
+			cut = false
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write([]byte(content[:100]))
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+	repoConf := github.RepoConfDef{DownloadURL: srv.URL + "/{{.Tool}}-{{.Version}}"}
+	vrsPath := filepath.Join(td, "versions")
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	viper.Set(tool+".install.skip-verify", true)
+	viper.Set("download.retries", 0)
+	defer viper.Set("download.retries", nil)
+
+	if err := install(&cobra.Command{}, "v1.0.0", tool, repoConf, InstallDownloadCmd, true); err == nil {
+		t.Fatalf("expected the truncated download to fail")
+	}
+	if err := install(&cobra.Command{}, "v1.0.0", tool, repoConf, InstallDownloadCmd, true); err != nil {
+		t.Fatalf("install resuming the download returned error: %v", err)
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=100-" {
+		t.Fatalf("expected the download to resume from byte 100, got ranges %q", ranges)
+	}
+	installed := func() string {
+		b, err := os.ReadFile(filepath.Join(vrsPath, tool, tool+"-v1.0.0"))
+		if err != nil {
+			t.Fatalf("failed to read installed binary: %v", err)
+		}
+		return string(b)
+	}
+	if installed() != content {
+		t.Fatalf("expected the resumed download to be complete")
+	}
+
+	// the file changed since the partial download, it is downloaded from scratch
+	if _, err := utils.RemoveVersion(vrsPath, tool, "v1.0.0"); err != nil {
+		t.Fatalf("failed to remove the version: %v", err)
+	}
+	cut, ranges = true, nil
+	if err := install(&cobra.Command{}, "v1.0.0", tool, repoConf, InstallDownloadCmd, true); err == nil {
+		t.Fatalf("expected the truncated download to fail")
+	}
+	content, etag = strings.Repeat("v2", 512), `"v2"`
+	if err := install(&cobra.Command{}, "v1.0.0", tool, repoConf, InstallDownloadCmd, true); err != nil {
+		t.Fatalf("install of the changed file returned error: %v", err)
+	}
+	if installed() != content {
+		t.Fatalf("expected the changed file to be downloaded from scratch")
+	}
+}
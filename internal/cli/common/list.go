@@ -5,12 +5,13 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/project"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 // newGithubListCommand creates a new 'list' command for the specified tool
 func newListCommand(tool string) *cobra.Command {
-	return &cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: fmt.Sprintf("List all installed %s versions", tool),
 		Long:  fmt.Sprintf(`Lists all the %s versions that are currently installed on the system.`, tool),
@@ -18,17 +19,27 @@ func newListCommand(tool string) *cobra.Command {
 			return list(cmd, tool)
 		},
 	}
+	addOutputFlag(listCmd, tool)
+	return listCmd
 }
 
 // list lists all installed versions of the specified tool
 func list(cmd *cobra.Command, tool string) error {
+	format, err := getOutputFormat(cmd, tool)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if format != OutputText {
+		out = redirectMessages(cmd)
+	}
 	vrsPath := viper.GetString("vrs-path")
 	versions, err := utils.ListInstalledVersions(vrsPath, tool)
 	if err != nil {
 		cmd.Println("Error listing available binaries:", err)
 		return err
 	}
-	if len(versions) == 0 {
+	if len(versions) == 0 && format == OutputText {
 		cmd.Printf("No %s versions installed.\n", tool)
 		return nil
 	}
@@ -43,13 +54,47 @@ func list(cmd *cobra.Command, tool string) error {
 		}
 	}
 
+	// the version pinned by the project file, if any
+	pinnedVersion, projectFile := project.PinnedVersion(tool)
+	pinnedResolved := ""
+	if pinnedVersion != "" {
+		pinnedResolved, _ = utils.ResolveVersion(pinnedVersion, versions)
+	}
+
+	if format != OutputText {
+		// the publish dates come from the releases cache, when there is one
+		cacheData, _ := utils.ReadFromCache(tool, 0)
+		o := VersionsOutput{
+			Tool:           tool,
+			CacheTimestamp: cacheTimestamp(cacheData.Timestamp),
+			Versions:       make([]*VersionRecord, 0, len(versions)),
+		}
+		for _, v := range versions {
+			r := newVersionRecord(v, cacheData.Releases)
+			r.Installed = true
+			r.InUse = v.Original() == currentVersion
+			r.Pinned = v.Original() == pinnedResolved
+			o.Versions = append(o.Versions, r)
+		}
+		return PrintOutput(out, format, o)
+	}
+
 	cmd.Printf("Available %s versions:\n", tool)
 	for _, v := range versions {
 		vrs := v.Original()
 		if vrs == currentVersion {
 			vrs += " *"
 		}
+		if pinnedResolved != "" && v.Original() == pinnedResolved {
+			vrs += " @"
+		}
 		cmd.Println(vrs)
 	}
+	if pinnedVersion != "" {
+		cmd.Printf("\nNote: '*' marks the global version, '@' the version pinned by %s.\n", projectFile)
+		if pinnedResolved == "" {
+			cmd.Printf("The pinned version %s is not installed. Run `vrsr sync` to install it.\n", pinnedVersion)
+		}
+	}
 	return nil
 }
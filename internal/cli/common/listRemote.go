@@ -25,6 +25,7 @@ func newGithubListRemoteCommand(tool string, repoConf github.RepoConfDef) *cobra
 		Short: fmt.Sprintf("List all remote %s versions from GitHub (sorted by semver)", tool),
 		Long: fmt.Sprintf("Lists all the remote %s versions available as GitHub releases (sorted by semver).\n\n"+
 			"In the list the versions currently installed are marked with a '+' symbol, while the version currently in use is marked with a '*' symbol.\n\n"+
+			"The releases are cached: '--force' only fetches the ones published since the last refresh, and costs no API rate limit when there are none.\n\n"+
 			"Note: By default pre-release versions (alpha, beta, rc) are hidden. Use '--devel' to include them", tool),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return listRemoteGithub(cmd, tool, repoConf)
@@ -46,6 +47,7 @@ func newGithubListRemoteCommand(tool string, repoConf github.RepoConfDef) *cobra
 		listRemoteCmd.PrintErr(err)
 		panic(err)
 	}
+	addOutputFlag(listRemoteCmd, tool)
 	return listRemoteCmd
 }
 
@@ -54,8 +56,19 @@ func listRemoteGithub(cmd *cobra.Command, tool string, repoConf github.RepoConfD
 	includeDevel = viper.GetBool(tool + ".list-remote.devel")
 	limit = viper.GetInt(tool + ".list-remote.limit")
 	forceRefresh = viper.GetBool(tool + ".list-remote.force")
-	ghc := github.New(nil)
-	releasesData, err := ghc.FetchAllReleases(tool, github.FetchOptions{
+	format, err := getOutputFormat(cmd, tool)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if format != OutputText {
+		out = redirectMessages(cmd)
+	}
+	ghc, err := github.NewFor(repoConf)
+	if err != nil {
+		return err
+	}
+	releasesData, err := ghc.FetchAllReleases(commandContext(cmd), tool, github.FetchOptions{
 		IncludeDevel: includeDevel,
 		Limit:        limit,
 		Force:        forceRefresh,
@@ -85,10 +98,29 @@ func listRemoteGithub(cmd *cobra.Command, tool string, repoConf github.RepoConfD
 			cmd.Println("Error listing available binaries:", err)
 		}
 	}
-	cmd.Println("Available versions to download:")
 	if limit > 0 && len(versions) > limit {
 		versions = versions[len(versions)-limit:]
 	}
+	if format != OutputText {
+		o := VersionsOutput{
+			Tool:           tool,
+			CacheTimestamp: cacheTimestamp(releasesData.Timestamp),
+			Versions:       make([]*VersionRecord, 0, len(versions)),
+		}
+		for _, v := range versions {
+			r := newVersionRecord(v, releasesData.Releases)
+			r.InUse = v.Original() == currentVersion
+			for _, lv := range localVersions {
+				if lv.Equal(v) {
+					r.Installed = true
+					break
+				}
+			}
+			o.Versions = append(o.Versions, r)
+		}
+		return PrintOutput(out, format, o)
+	}
+	cmd.Println("Available versions to download:")
 	for _, v := range versions {
 		vrs := v.Original()
 		if vrs == currentVersion {
@@ -107,7 +139,9 @@ func listRemoteGithub(cmd *cobra.Command, tool string, repoConf github.RepoConfD
 	if !includeDevel {
 		cmd.Println("\nNote: Pre-release versions (alpha, beta, rc) are hidden. Use '--devel' to include them.")
 	}
-	if !forceRefresh && time.Since(releasesData.Timestamp) > 5*time.Minute {
+	if utils.IsOffline() {
+		cmd.Printf("\nNote: Offline mode, the results shown above were cached %s.\n", humanize.Time(releasesData.Timestamp))
+	} else if !forceRefresh && time.Since(releasesData.Timestamp) > 5*time.Minute {
 		cmd.Printf("\nNote: The results shown above were cached %s. You can use the '-f' flag to force a refresh of the list.\n", humanize.Time(releasesData.Timestamp))
 	}
 	return nil
@@ -1,10 +1,18 @@
 package common
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	gh "github.com/google/go-github/v78/github"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/github"
+	"go.yaml.in/yaml/v3"
 )
 
 func TestListRemoteFlags(t *testing.T) {
@@ -38,3 +46,47 @@ func TestListRemoteFlags(t *testing.T) {
 		t.Fatalf("expected default force=false")
 	}
 }
+
+func TestListRemote_YAMLOutput(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	tool := "yamltool"
+	published := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cacheReleases(t, tool, []*gh.RepositoryRelease{
+		{TagName: gh.Ptr("v1.0.0"), PublishedAt: &gh.Timestamp{Time: published}},
+		{TagName: gh.Ptr("v1.1.0-rc.1")},
+	})
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vrsPath, tool, tool+"-v1.0.0"), []byte("x"), 0o755); err != nil {
+		t.Fatalf("failed to write binary: %v", err)
+	}
+	viper.Set(tool+".list-remote.devel", true)
+	viper.Set(tool+".list-remote.output", OutputYAML)
+
+	cmd := &cobra.Command{Use: "list-remote"}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	cmd.SetErr(io.Discard)
+	if err := listRemoteGithub(cmd, tool, github.RepoConfDef{}); err != nil {
+		t.Fatalf("list-remote returned error: %v", err)
+	}
+	var o VersionsOutput
+	if err := yaml.Unmarshal([]byte(sb.String()), &o); err != nil {
+		t.Fatalf("expected YAML output, got %q: %v", sb.String(), err)
+	}
+	if o.Tool != tool || o.CacheTimestamp == nil || len(o.Versions) != 2 {
+		t.Fatalf("unexpected output: %+v", o)
+	}
+	stable, rc := o.Versions[0], o.Versions[1]
+	if stable.Version != "v1.0.0" || !stable.Installed || stable.Prerelease || stable.PublishedAt == nil || !stable.PublishedAt.Equal(published) {
+		t.Fatalf("unexpected record for v1.0.0: %+v", stable)
+	}
+	if rc.Version != "v1.1.0-rc.1" || rc.Installed || !rc.Prerelease {
+		t.Fatalf("unexpected record for v1.1.0-rc.1: %+v", rc)
+	}
+}
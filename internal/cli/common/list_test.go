@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 func TestList_NoVersionsInstalled(t *testing.T) {
@@ -77,3 +79,157 @@ func TestList_MarksInUseVersion(t *testing.T) {
 		t.Fatalf("expected other versions to be listed, got: %s", out)
 	}
 }
+
+func TestList_MarksProjectPinnedVersion(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	tool := "pintool"
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := os.WriteFile(filepath.Join(toolDir, tool+"-"+v), []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to write version: %v", err)
+		}
+	}
+	// pin 1.0.0 in a project file of the working directory
+	projectDir := filepath.Join(td, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ".vrsr.yaml"), []byte("tools:\n  pintool: 1.0.0\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	t.Chdir(projectDir)
+
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+
+	cmd := &cobra.Command{}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	if err := list(cmd, tool); err != nil {
+		t.Fatalf("list returned error: %v", err)
+	}
+	out := sb.String()
+	if !strings.Contains(out, "v1.0.0 @") {
+		t.Fatalf("expected pinned version to be marked with '@', got: %s", out)
+	}
+	if strings.Contains(out, "v2.0.0 @") {
+		t.Fatalf("expected only the pinned version to be marked, got: %s", out)
+	}
+}
+
+func TestList_AdoptsLegacyFilesWithDashesAndPrereleases(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "kube-apiserver"
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	for _, v := range []string{"v1.30.0", "v1.31.0-rc.1"} {
+		if err := os.WriteFile(filepath.Join(toolDir, tool+"-"+v), []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to write version: %v", err)
+		}
+	}
+	// leftovers of an interrupted download must be ignored
+	if err := os.WriteFile(filepath.Join(toolDir, tool+"-download-1234"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(toolDir, tool+"-v1.31.0-rc.1"), filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+
+	cmd := &cobra.Command{}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	if err := list(cmd, tool); err != nil {
+		t.Fatalf("list returned error: %v", err)
+	}
+	out := sb.String()
+	if !strings.Contains(out, "v1.30.0\n") || !strings.Contains(out, "v1.31.0-rc.1 *") {
+		t.Fatalf("expected both versions listed and the pre-release in use, got: %s", out)
+	}
+	if strings.Contains(out, "download") {
+		t.Fatalf("expected temp files to be ignored, got: %s", out)
+	}
+
+	m, err := utils.ReadManifest(vrsPath, tool, "v1.31.0-rc.1")
+	if err != nil {
+		t.Fatalf("expected legacy binary to be adopted: %v", err)
+	}
+	if !m.Migrated || m.Version != "1.31.0-rc.1" || m.Tool != tool {
+		t.Fatalf("unexpected migrated manifest: %+v", m)
+	}
+	// reading adopts the binaries in memory only, writing their manifests is up to the installs
+	manifestPath := filepath.Join(toolDir, ".manifests", "v1.31.0-rc.1.json")
+	if _, err := os.Stat(manifestPath); !os.IsNotExist(err) {
+		t.Fatalf("expected list not to write manifests, got %v", err)
+	}
+	if err := utils.AdoptInstalled(vrsPath, tool); err != nil {
+		t.Fatalf("AdoptInstalled returned error: %v", err)
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		t.Fatalf("expected the manifest to be written: %v", err)
+	}
+}
+
+func TestList_JSONOutput(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "jsontool"
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := os.WriteFile(filepath.Join(toolDir, tool+"-"+v), []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to write binary: %v", err)
+		}
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(toolDir, tool+"-v2.0.0"), filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	viper.Set(tool+".list.output", OutputJSON)
+
+	cmd := &cobra.Command{Use: "list"}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	if err := list(cmd, tool); err != nil {
+		t.Fatalf("list returned error: %v", err)
+	}
+	var o VersionsOutput
+	if err := json.Unmarshal([]byte(sb.String()), &o); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", sb.String(), err)
+	}
+	// no releases cache yet
+	if o.Tool != tool || o.CacheTimestamp != nil || len(o.Versions) != 2 {
+		t.Fatalf("unexpected output: %+v", o)
+	}
+	if v := o.Versions[0]; v.Version != "v1.0.0" || !v.Installed || v.InUse {
+		t.Fatalf("unexpected record for v1.0.0: %+v", v)
+	}
+	if v := o.Versions[1]; v.Version != "v2.0.0" || !v.InUse {
+		t.Fatalf("unexpected record for v2.0.0: %+v", v)
+	}
+
+	viper.Set(tool+".list.output", "xml")
+	if err := list(cmd, tool); err == nil {
+		t.Fatalf("expected unsupported output format to fail")
+	}
+}
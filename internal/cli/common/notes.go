@@ -0,0 +1,47 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stepbeta/vrsr/internal/github"
+)
+
+// newNotesCommand creates a new 'notes' command for the specified tool
+func newNotesCommand(tool string, repoConf github.RepoConfDef) *cobra.Command {
+	return &cobra.Command{
+		Use:   "notes <version>",
+		Short: fmt.Sprintf("Show the release notes of a %s version", tool),
+		Long: fmt.Sprintf("Prints the release notes published on GitHub for the %s version.\n\n"+
+			"The notes are stored apart from the releases cache: they are only read when asked for, "+
+			"and fetched from GitHub when missing.", tool),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return notes(cmd, args[0], tool, repoConf)
+		},
+	}
+}
+
+// notes prints the release notes of the specified version of the tool
+func notes(cmd *cobra.Command, input, tool string, repoConf github.RepoConfDef) error {
+	vrs, err := ResolveRemoteVersion(cmd, input, tool, repoConf)
+	if err != nil {
+		return err
+	}
+	ghc, err := github.NewFor(repoConf)
+	if err != nil {
+		return err
+	}
+	body, err := ghc.ReleaseNotes(commandContext(cmd), tool, vrs, repoConf)
+	if err != nil {
+		return err
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		cmd.Printf("%s version %s has no release notes\n", tool, vrs)
+		return nil
+	}
+	cmd.Println(body)
+	return nil
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestNotes_ReadsStoredReleaseNotes(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	viper.Set("vrs-path", filepath.Join(td, "versions"))
+	viper.Set("bin-path", filepath.Join(td, "bin"))
+	viper.Set("offline", true)
+	t.Cleanup(func() { viper.Set("offline", false) })
+	tool := "notestool"
+	cacheReleases(t, tool, []*gh.RepositoryRelease{
+		{TagName: gh.Ptr("v1.1.0"), Body: gh.Ptr("## Changes\n\n- faster\n")},
+		{TagName: gh.Ptr("v1.0.0")},
+	})
+
+	cachePath, err := utils.GetCachePath(tool)
+	if err != nil {
+		t.Fatalf("failed to get cache path: %v", err)
+	}
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("failed to read cache: %v", err)
+	}
+	if strings.Contains(string(content), "faster") {
+		t.Fatalf("expected the release notes to be kept out of the cache, got %s", content)
+	}
+
+	cmd := &cobra.Command{Use: "notes"}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	cmd.SetErr(io.Discard)
+	if err := notes(cmd, "1.1", tool, github.RepoConfDef{}); err != nil {
+		t.Fatalf("notes returned error: %v", err)
+	}
+	if !strings.Contains(sb.String(), "Resolved 1.1 to notestool version v1.1.0") || !strings.HasSuffix(sb.String(), "- faster\n") {
+		t.Fatalf("unexpected output: %q", sb.String())
+	}
+
+	// the notes of v1.0.0 are not stored and cannot be fetched offline
+	sb.Reset()
+	if err := notes(cmd, "v1.0.0", tool, github.RepoConfDef{}); err == nil {
+		t.Fatalf("expected an error for missing notes while offline, got %q", sb.String())
+	}
+}
+
+func TestReadFromCache_MigratesLegacyCache(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	tool := "legacytool"
+	legacy, err := json.Marshal(map[string]any{
+		"timestamp": "2025-01-02T03:04:05Z",
+		"etag":      `"abc"`,
+		"releases": []*gh.RepositoryRelease{{
+			TagName:    gh.Ptr("v2.0.0-rc.1"),
+			Prerelease: gh.Ptr(true),
+			Body:       gh.Ptr("release candidate"),
+			HTMLURL:    gh.Ptr("https://github.com/o/r/releases/tag/v2.0.0-rc.1"),
+			Assets: []*gh.ReleaseAsset{{
+				ID:     gh.Ptr(int64(7)),
+				Name:   gh.Ptr("legacytool-linux-amd64"),
+				Size:   gh.Ptr(42),
+				Digest: gh.Ptr("sha256:00ff"),
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal legacy cache: %v", err)
+	}
+	cachePath, err := utils.GetCachePath(tool)
+	if err != nil {
+		t.Fatalf("failed to get cache path: %v", err)
+	}
+	if err := utils.EnsurePathExists(filepath.Dir(cachePath)); err != nil {
+		t.Fatalf("failed to create cache dir: %v", err)
+	}
+	if err := os.WriteFile(cachePath, legacy, 0o644); err != nil {
+		t.Fatalf("failed to write legacy cache: %v", err)
+	}
+
+	data, err := utils.ReadFromCache(tool, 0)
+	if err != nil {
+		t.Fatalf("ReadFromCache returned error: %v", err)
+	}
+	if data.Version != utils.CacheVersion || data.ETag != `"abc"` || len(data.Releases) != 1 {
+		t.Fatalf("unexpected migrated cache: %+v", data)
+	}
+	rel := data.Releases[0]
+	if rel.Tag != "v2.0.0-rc.1" || rel.Semver != "2.0.0-rc.1" || !rel.Prerelease || len(rel.Assets) != 1 {
+		t.Fatalf("unexpected migrated release: %+v", rel)
+	}
+	if a := rel.Assets[0]; a.ID != 7 || a.Name != "legacytool-linux-amd64" || a.Size != 42 || a.Digest != "sha256:00ff" {
+		t.Fatalf("unexpected migrated asset: %+v", a)
+	}
+	if body, ok, err := utils.ReadReleaseNotes(tool, "v2.0.0-rc.1"); err != nil || !ok || body != "release candidate" {
+		t.Fatalf("expected the release notes to be moved, got %q, %v, %v", body, ok, err)
+	}
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("failed to read cache: %v", err)
+	}
+	if strings.Contains(string(content), "html_url") || strings.Contains(string(content), "release candidate") {
+		t.Fatalf("expected the cache to be rewritten in the compact schema, got %s", content)
+	}
+}
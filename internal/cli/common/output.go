@@ -0,0 +1,145 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+	"go.yaml.in/yaml/v3"
+)
+
+// Output formats accepted by the "-o" flag.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+var outputFormat string
+
+// VersionRecord is the machine-readable description of a tool version.
+type VersionRecord struct {
+	Version    string `json:"version" yaml:"version"`
+	Installed  bool   `json:"installed" yaml:"installed"`
+	InUse      bool   `json:"in_use" yaml:"in_use"`
+	Pinned     bool   `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	Prerelease bool   `json:"prerelease" yaml:"prerelease"`
+	// PublishedAt is the release date, known when the releases cache includes the version.
+	PublishedAt *time.Time `json:"published_at,omitempty" yaml:"published_at,omitempty"`
+}
+
+// VersionsOutput is the machine-readable output of the list commands.
+type VersionsOutput struct {
+	Tool string `json:"tool" yaml:"tool"`
+	// CacheTimestamp is when the releases cache the data comes from was written.
+	CacheTimestamp *time.Time       `json:"cache_timestamp,omitempty" yaml:"cache_timestamp,omitempty"`
+	Versions       []*VersionRecord `json:"versions" yaml:"versions"`
+}
+
+// VersionOutput is the machine-readable output of the commands dealing with a single version.
+type VersionOutput struct {
+	Tool string `json:"tool" yaml:"tool"`
+	// Source tells what selected the version in use (environment, project or global).
+	Source         string     `json:"source,omitempty" yaml:"source,omitempty"`
+	CacheTimestamp *time.Time `json:"cache_timestamp,omitempty" yaml:"cache_timestamp,omitempty"`
+	VersionRecord  `yaml:",inline"`
+}
+
+// addOutputFlag adds the "-o, --output" flag to the subcommand of the tool.
+func addOutputFlag(cmd *cobra.Command, tool string) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", OutputText, fmt.Sprintf("Output format: %s, %s or %s", OutputText, OutputJSON, OutputYAML))
+	if err := viper.BindPFlag(fmt.Sprintf("%s.%s.output", tool, cmd.Name()), cmd.Flags().Lookup("output")); err != nil {
+		cmd.PrintErr(err)
+		panic(err)
+	}
+}
+
+// getOutputFormat returns the validated output format of the subcommand of the tool.
+func getOutputFormat(cmd *cobra.Command, tool string) (string, error) {
+	return ValidateOutputFormat(viper.GetString(fmt.Sprintf("%s.%s.output", tool, cmd.Name())))
+}
+
+// ValidateOutputFormat checks the output format, defaulting to text.
+func ValidateOutputFormat(format string) (string, error) {
+	if format == "" {
+		return OutputText, nil
+	}
+	if !slices.Contains([]string{OutputText, OutputJSON, OutputYAML}, format) {
+		return "", fmt.Errorf("unsupported output format %q, use %s, %s or %s", format, OutputText, OutputJSON, OutputYAML)
+	}
+	return format, nil
+}
+
+// redirectMessages sends the human-readable messages of the command to stderr, so that
+// stdout only holds the machine-readable output, to be written to the returned writer.
+func redirectMessages(cmd *cobra.Command) io.Writer {
+	out := cmd.OutOrStdout()
+	stderr := cmd.ErrOrStderr()
+	cmd.SetOut(stderr)
+	if p := cmd.Parent(); p != nil {
+		// sibling commands run on the way (e.g. use after install) print there
+		p.SetOut(stderr)
+	}
+	return out
+}
+
+// PrintOutput writes v to out in the given machine-readable format.
+func PrintOutput(out io.Writer, format string, v any) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// newVersionRecord returns the record of the version, with the publish date taken from releases.
+func newVersionRecord(v *semver.Version, releases []utils.Release) *VersionRecord {
+	r := &VersionRecord{
+		Version:    v.Original(),
+		Prerelease: v.Prerelease() != "",
+	}
+	for _, rel := range releases {
+		if rel.Tag == v.Original() && rel.PublishedAt != nil {
+			r.PublishedAt = rel.PublishedAt
+			break
+		}
+	}
+	return r
+}
+
+// newVersionOutput returns the machine-readable description of the tool version.
+func newVersionOutput(tool, vrs, source string) VersionOutput {
+	cacheData, _ := utils.ReadFromCache(tool, 0)
+	o := VersionOutput{Tool: tool, Source: source, CacheTimestamp: cacheTimestamp(cacheData.Timestamp)}
+	if v, err := semver.NewVersion(vrs); err == nil {
+		o.VersionRecord = *newVersionRecord(v, cacheData.Releases)
+	} else {
+		o.Version = vrs
+	}
+	o.Installed = utils.IsToolInstalled(tool, vrs)
+	o.InUse = utils.IsToolInUse(tool, vrs)
+	return o
+}
+
+// cacheTimestamp returns the timestamp of the releases cache, nil when there is none.
+func cacheTimestamp(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+var (
+	pruneKeep      int
+	pruneOlderThan string
+)
+
+// newPruneCommand creates a new 'prune' command for the specified tool
+func newPruneCommand(tool string) *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: fmt.Sprintf("Remove old installed %s versions", tool),
+		Long: fmt.Sprintf("Removes the installed %s versions that are not among the \"--keep\" newest ones "+
+			"and/or that were installed more than \"--older-than\" ago (e.g. 90d, 2w, 36h).\n\n"+
+			"The version in use and the one pinned by the project file are always kept.", tool),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return prune(cmd, tool)
+		},
+	}
+	// Bind flags to Viper keys so config file / env / flags work together.
+	pruneCmd.Flags().IntVarP(&pruneKeep, "keep", "k", 0, "Number of newest versions to keep")
+	if err := viper.BindPFlag(fmt.Sprintf("%s.prune.keep", tool), pruneCmd.Flags().Lookup("keep")); err != nil {
+		pruneCmd.PrintErr(err)
+		panic(err)
+	}
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Only remove versions installed longer ago than this (e.g. 90d)")
+	if err := viper.BindPFlag(fmt.Sprintf("%s.prune.older-than", tool), pruneCmd.Flags().Lookup("older-than")); err != nil {
+		pruneCmd.PrintErr(err)
+		panic(err)
+	}
+	return pruneCmd
+}
+
+// prune removes old versions of the tool, keeping the active and project-pinned ones
+func prune(cmd *cobra.Command, tool string) error {
+	pruneKeep = viper.GetInt(tool + ".prune.keep")
+	pruneOlderThan = viper.GetString(tool + ".prune.older-than")
+	if pruneKeep <= 0 && pruneOlderThan == "" {
+		return fmt.Errorf("specify which versions to remove with --keep and/or --older-than")
+	}
+	var cutoff time.Time
+	if pruneOlderThan != "" {
+		age, err := utils.ParseAge(pruneOlderThan)
+		if err != nil {
+			return err
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	vrsPath := viper.GetString("vrs-path")
+	unlock, err := lockInstalled(cmd, vrsPath, tool)
+	if err != nil {
+		cmd.Println("Error locking vrs path:", err)
+		return err
+	}
+	defer unlock()
+	versions, err := utils.ListInstalledVersions(vrsPath, tool)
+	if err != nil {
+		cmd.Println("Error listing available binaries:", err)
+		return err
+	}
+	currentVersion := ""
+	if binPath := viper.GetString("bin-path"); binPath != "" {
+		currentVersion, err = utils.GetVrsInUse(binPath, tool)
+		if err != nil {
+			cmd.Println("Error getting current version:", err)
+		}
+	}
+	pinnedVersion, _ := project.PinnedVersion(tool)
+	if pinnedVersion != "" {
+		// the pin may be a constraint, protect the installed version it resolves to
+		pinnedVersion, _ = utils.ResolveVersion(pinnedVersion, versions)
+	}
+
+	var reclaimed int64
+	var errs []error
+	removed := 0
+	for i, v := range versions {
+		vrs := v.Original()
+		// versions are sorted oldest first
+		if pruneKeep > 0 && len(versions)-i <= pruneKeep {
+			continue
+		}
+		if !cutoff.IsZero() {
+			installedAt, err := utils.InstalledAt(vrsPath, tool, vrs)
+			if err != nil || installedAt.After(cutoff) {
+				continue
+			}
+		}
+		if vrs == currentVersion {
+			cmd.Printf("Keeping %s version %s (in use)\n", tool, vrs)
+			continue
+		}
+		if vrs == pinnedVersion {
+			cmd.Printf("Keeping %s version %s (pinned by the project)\n", tool, vrs)
+			continue
+		}
+		size, err := utils.RemoveVersion(vrsPath, tool, vrs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s version %s: %w", tool, vrs, err))
+			continue
+		}
+		reclaimed += size
+		removed++
+		cmd.Printf("Removed %s version %s\n", tool, vrs)
+	}
+	if removed == 0 {
+		cmd.Printf("No %s versions to prune\n", tool)
+	}
+	cmd.Printf("Reclaimed %s of disk space\n", humanize.Bytes(uint64(reclaimed)))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestPrune_KeepsNewestActiveAndPinned(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "prtool"
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0"} {
+		p := filepath.Join(toolDir, tool+"-"+v)
+		if err := os.WriteFile(p, []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to write version: %v", err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatalf("failed to age version: %v", err)
+		}
+	}
+	// v1.4.0 was installed recently
+	if err := os.Chtimes(filepath.Join(toolDir, tool+"-v1.4.0"), time.Now(), time.Now()); err != nil {
+		t.Fatalf("failed to touch version: %v", err)
+	}
+	// v1.0.0 is in use, v1.1.0 is pinned
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(toolDir, tool+"-v1.0.0"), filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(td, ".vrsr.yaml"), []byte("tools:\n  prtool: 1.1.0\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	t.Chdir(td)
+
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	cmd := &cobra.Command{}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+
+	// nothing given
+	if err := prune(cmd, tool); err == nil {
+		t.Fatalf("expected error without --keep or --older-than")
+	}
+
+	viper.Set(tool+".prune.keep", 1)
+	viper.Set(tool+".prune.older-than", "90d")
+	defer viper.Set(tool+".prune.keep", 0)
+	defer viper.Set(tool+".prune.older-than", "")
+	if err := prune(cmd, tool); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+	for v, kept := range map[string]bool{"v1.0.0": true, "v1.1.0": true, "v1.2.0": false, "v1.3.0": false, "v1.4.0": true} {
+		_, err := os.Stat(filepath.Join(toolDir, tool+"-"+v))
+		if kept && err != nil {
+			t.Fatalf("expected %s to be kept: %v", v, err)
+		}
+		if !kept && !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed", v)
+		}
+	}
+	if !strings.Contains(sb.String(), "Reclaimed 2 B") {
+		t.Fatalf("expected reclaimed space to be reported, got: %s", sb.String())
+	}
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/shim"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// ToolStatus summarizes the state of a tool on this machine.
+type ToolStatus struct {
+	Tool string `json:"tool" yaml:"tool"`
+	// Active is the global version in use.
+	Active    string `json:"active,omitempty" yaml:"active,omitempty"`
+	Installed int    `json:"installed" yaml:"installed"`
+	// DiskUsage is the size in bytes of the installed binaries.
+	DiskUsage int64 `json:"disk_usage" yaml:"disk_usage"`
+	// Latest is the newest stable version in the releases cache.
+	Latest          string     `json:"latest,omitempty" yaml:"latest,omitempty"`
+	CacheTimestamp  *time.Time `json:"cache_timestamp,omitempty" yaml:"cache_timestamp,omitempty"`
+	UpdateAvailable bool       `json:"update_available" yaml:"update_available"`
+	// Pinned is the version pinned by the project file, PinnedResolved the installed version it resolves to.
+	Pinned         string `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	PinnedResolved string `json:"pinned_resolved,omitempty" yaml:"pinned_resolved,omitempty"`
+	ProjectFile    string `json:"project_file,omitempty" yaml:"project_file,omitempty"`
+	// Drift is set when the symlink in bin-path does not point at the pinned version.
+	Drift bool `json:"drift" yaml:"drift"`
+}
+
+// GetToolStatus collects the status of the tool. Upstream versions are only read from
+// the releases cache, so that no network access is needed.
+func GetToolStatus(tool string) (ToolStatus, error) {
+	st := ToolStatus{Tool: tool}
+	vrsPath := viper.GetString("vrs-path")
+	versions, err := utils.ListInstalledVersions(vrsPath, tool)
+	if err != nil {
+		return st, err
+	}
+	st.Installed = len(versions)
+	if st.DiskUsage, err = utils.DiskUsage(vrsPath, tool); err != nil {
+		return st, err
+	}
+	if st.Active, err = utils.GetVrsInUse(viper.GetString("bin-path"), tool); err != nil {
+		return st, err
+	}
+
+	cacheData, _ := utils.ReadFromCache(tool, 0)
+	st.CacheTimestamp = cacheTimestamp(cacheData.Timestamp)
+	if upstream := utils.SemverFromReleases(cacheData.Releases, false); len(upstream) > 0 {
+		latest := upstream[len(upstream)-1]
+		st.Latest = latest.Original()
+		if active, err := semver.NewVersion(st.Active); err == nil {
+			st.UpdateAvailable = latest.GreaterThan(active)
+		}
+	}
+
+	st.Pinned, st.ProjectFile = project.PinnedVersion(tool)
+	if st.Pinned != "" {
+		st.PinnedResolved, _ = utils.ResolveVersion(st.Pinned, versions)
+		// shims run the pinned version by themselves, only symlinks can drift
+		st.Drift = viper.GetString("mode") != shim.ModeShim && (st.PinnedResolved == "" || st.PinnedResolved != st.Active)
+	}
+	return st, nil
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/shim"
+)
+
+func TestGetToolStatus(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "stattool"
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	viper.Set("mode", shim.ModeSymlink)
+
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	for v, size := range map[string]int{"v1.0.0": 10, "v1.1.0": 20} {
+		if err := os.WriteFile(filepath.Join(toolDir, tool+"-"+v), make([]byte, size), 0o755); err != nil {
+			t.Fatalf("failed to write binary: %v", err)
+		}
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(toolDir, tool+"-v1.0.0"), filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	cacheReleases(t, tool, []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.2.0")}, {TagName: gh.Ptr("v2.0.0-rc.1")}})
+
+	projectDir := filepath.Join(td, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, project.FileName), []byte("tools:\n  stattool: \"1.1\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	t.Chdir(projectDir)
+
+	st, err := GetToolStatus(tool)
+	if err != nil {
+		t.Fatalf("GetToolStatus returned error: %v", err)
+	}
+	if st.Active != "v1.0.0" || st.Installed != 2 || st.DiskUsage != 30 {
+		t.Fatalf("unexpected installed state: %+v", st)
+	}
+	// pre-releases are not offered as updates
+	if st.Latest != "v1.2.0" || !st.UpdateAvailable || st.CacheTimestamp == nil {
+		t.Fatalf("unexpected upstream state: %+v", st)
+	}
+	if st.Pinned != "1.1" || st.PinnedResolved != "v1.1.0" || !st.Drift {
+		t.Fatalf("expected drift from the pinned version: %+v", st)
+	}
+
+	// shims always run the pinned version
+	viper.Set("mode", shim.ModeShim)
+	defer viper.Set("mode", shim.ModeSymlink)
+	if st, err := GetToolStatus(tool); err != nil || st.Drift {
+		t.Fatalf("expected no drift in shim mode, got %+v, %v", st, err)
+	}
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+var forceUninstall bool
+
+// newUninstallCommand creates a new 'uninstall' command for the specified tool
+func newUninstallCommand(tool string) *cobra.Command {
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall <version...>",
+		Short: fmt.Sprintf("Remove installed %s versions", tool),
+		Long: fmt.Sprintf("Removes the specified %s versions from the \"vrs-path\".\n\n"+
+			"The version currently in use is only removed when \"--force\" is passed.", tool),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return uninstall(cmd, args, tool)
+		},
+	}
+	// Bind flags to Viper keys so config file / env / flags work together.
+	uninstallCmd.Flags().BoolVarP(&forceUninstall, "force", "f", false, "Remove the version even if it is in use")
+	if err := viper.BindPFlag(fmt.Sprintf("%s.uninstall.force", tool), uninstallCmd.Flags().Lookup("force")); err != nil {
+		uninstallCmd.PrintErr(err)
+		panic(err)
+	}
+	return uninstallCmd
+}
+
+// uninstall removes the specified versions of the tool
+func uninstall(cmd *cobra.Command, versions []string, tool string) error {
+	forceUninstall = viper.GetBool(tool + ".uninstall.force")
+	vrsPath := viper.GetString("vrs-path")
+	binPath := viper.GetString("bin-path")
+	unlock, err := lockInstalled(cmd, vrsPath, tool)
+	if err != nil {
+		cmd.Println("Error locking vrs path:", err)
+		return err
+	}
+	defer unlock()
+
+	var reclaimed int64
+	var errs []error
+	for _, vrs := range versions {
+		if !utils.IsToolInstalled(tool, vrs) {
+			cmd.Printf("%s version %s is not installed, skipping\n", tool, vrs)
+			continue
+		}
+		inUse := utils.IsToolInUse(tool, vrs)
+		if inUse && !forceUninstall {
+			errs = append(errs, fmt.Errorf("%s version %s is in use, pass --force to remove it anyway", tool, vrs))
+			continue
+		}
+		size, err := utils.RemoveVersion(vrsPath, tool, vrs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s version %s: %w", tool, vrs, err))
+			continue
+		}
+		reclaimed += size
+		cmd.Printf("Removed %s version %s\n", tool, vrs)
+		if inUse {
+			// do not leave a dangling link behind
+			if err := deactivate(binPath, tool); err != nil {
+				errs = append(errs, err)
+			}
+			cmd.Printf("Warning: no %s version is in use anymore. Run `vrsr %s use <version>` to select one\n", tool, tool)
+		}
+	}
+	cmd.Printf("Reclaimed %s of disk space\n", humanize.Bytes(uint64(reclaimed)))
+	return errors.Join(errs...)
+}
+
+// deactivate removes the active version of the tool from the bin path
+func deactivate(binPath, tool string) error {
+	target := filepath.Join(binPath, tool)
+	if utils.IsShim(target) {
+		// the shim is kept, as environment and project versions still apply
+		return utils.RemoveGlobalVersion(binPath, tool)
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove symlink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestUninstall_RefusesInUseVersionWithoutForce(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "untool"
+	toolDir := filepath.Join(vrsPath, tool)
+	if err := os.MkdirAll(toolDir, 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := os.WriteFile(filepath.Join(toolDir, tool+"-"+v), []byte("12345"), 0o755); err != nil {
+			t.Fatalf("failed to write version: %v", err)
+		}
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(toolDir, tool+"-v2.0.0"), filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	viper.Set(tool+".uninstall.force", false)
+
+	cmd := &cobra.Command{}
+	var sb strings.Builder
+	cmd.SetOut(&sb)
+	if err := uninstall(cmd, []string{"v1.0.0", "v2.0.0"}, tool); err == nil {
+		t.Fatalf("expected error when removing the version in use")
+	}
+	if _, err := os.Stat(filepath.Join(toolDir, tool+"-v1.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected v1.0.0 to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(toolDir, tool+"-v2.0.0")); err != nil {
+		t.Fatalf("expected in-use v2.0.0 to be kept: %v", err)
+	}
+	if !strings.Contains(sb.String(), "Reclaimed 5 B") {
+		t.Fatalf("expected reclaimed space to be reported, got: %s", sb.String())
+	}
+
+	viper.Set(tool+".uninstall.force", true)
+	defer viper.Set(tool+".uninstall.force", false)
+	if err := uninstall(cmd, []string{"v2.0.0"}, tool); err != nil {
+		t.Fatalf("expected forced uninstall to succeed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(binPath, tool)); !os.IsNotExist(err) {
+		t.Fatalf("expected the dangling symlink to be removed")
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// Outdated reports the upgrades available for the version of a tool in use.
+type Outdated struct {
+	Tool string `json:"tool" yaml:"tool"`
+	// Current is the version in use, empty when there is none.
+	Current string `json:"current,omitempty" yaml:"current,omitempty"`
+	// Patch, Minor and Major are the newest versions at each upgrade level, empty when up to date.
+	Patch string `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Minor string `json:"minor,omitempty" yaml:"minor,omitempty"`
+	Major string `json:"major,omitempty" yaml:"major,omitempty"`
+	// Pinned is the version pinned by the project file, which upgrades do not leave.
+	Pinned         string     `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	ProjectFile    string     `json:"project_file,omitempty" yaml:"project_file,omitempty"`
+	CacheTimestamp *time.Time `json:"cache_timestamp,omitempty" yaml:"cache_timestamp,omitempty"`
+}
+
+// GetOutdated compares the version of the tool in use against its releases, read from the
+// releases cache when available.
+func GetOutdated(ctx context.Context, tool string, repoConf github.RepoConfDef) (Outdated, error) {
+	o := Outdated{Tool: tool}
+	current, candidates, cachedAt, err := upgradeCandidates(ctx, tool, repoConf)
+	if err != nil || current == nil {
+		return o, err
+	}
+	o.Current = current.Original()
+	o.CacheTimestamp = cacheTimestamp(cachedAt)
+	o.Pinned, o.ProjectFile = project.PinnedVersion(tool)
+	for level, newest := range map[string]*string{utils.LevelPatch: &o.Patch, utils.LevelMinor: &o.Minor, utils.LevelMajor: &o.Major} {
+		if v := utils.NewestUpgrade(current, candidates, level); v != nil {
+			*newest = v.Original()
+		}
+	}
+	return o, nil
+}
+
+// UpgradeTarget returns the version in use of the tool and the newest release it can be upgraded
+// to at the given level. Upgrades stay within the version pinned by the project file, if any, and
+// do not go past the tag locked by the project lockfile, so that they are verified against it.
+// Both are empty when no version is in use, the target alone when the tool is up to date.
+func UpgradeTarget(ctx context.Context, tool string, repoConf github.RepoConfDef, level string) (string, string, error) {
+	current, candidates, _, err := upgradeCandidates(ctx, tool, repoConf)
+	if err != nil || current == nil {
+		return "", "", err
+	}
+	if pinned, _ := project.PinnedVersion(tool); pinned != "" {
+		allowed := make([]*semver.Version, 0, len(candidates))
+		for _, v := range candidates {
+			if _, err := utils.ResolveVersion(pinned, []*semver.Version{v}); err == nil {
+				allowed = append(allowed, v)
+			}
+		}
+		candidates = allowed
+	}
+	lock, err := project.FindLock()
+	if err != nil {
+		return "", "", err
+	}
+	if lt, ok := lock.Tool(tool); ok {
+		locked, err := semver.NewVersion(lt.Tag)
+		if err != nil {
+			return "", "", fmt.Errorf("tag %s locked by %s is not a semantic version: %w", lt.Tag, lock.Path, err)
+		}
+		allowed := make([]*semver.Version, 0, len(candidates))
+		for _, v := range candidates {
+			if !v.GreaterThan(locked) {
+				allowed = append(allowed, v)
+			}
+		}
+		candidates = allowed
+	}
+	target := utils.NewestUpgrade(current, candidates, level)
+	if target == nil {
+		return current.Original(), "", nil
+	}
+	return current.Original(), target.Original(), nil
+}
+
+// upgradeCandidates returns the version of the tool in use, if any, along with the known
+// releases and when they were cached
+func upgradeCandidates(ctx context.Context, tool string, repoConf github.RepoConfDef) (*semver.Version, []*semver.Version, time.Time, error) {
+	vrs, err := utils.GetVrsInUse(viper.GetString("bin-path"), tool)
+	if err != nil || vrs == "" {
+		return nil, nil, time.Time{}, err
+	}
+	current, err := semver.NewVersion(vrs)
+	if err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("version in use %s is not a semantic version: %w", vrs, err)
+	}
+	ghc, err := github.NewFor(repoConf)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	releasesData, err := ghc.FetchAllReleases(ctx, tool, github.FetchOptions{RepoConf: repoConf})
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	return current, utils.SemverFromReleases(releasesData.Releases, false), releasesData.Timestamp, nil
+}
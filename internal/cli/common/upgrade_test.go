@@ -0,0 +1,85 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestOutdatedAndUpgradeTarget(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "uptool"
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	t.Chdir(td)
+
+	var rels []*gh.RepositoryRelease
+	for _, v := range []string{"v1.29.0", "v1.29.3", "v1.30.0", "v1.30.2", "v1.31.0-rc.1", "v2.0.1"} {
+		rels = append(rels, &gh.RepositoryRelease{TagName: gh.Ptr(v)})
+	}
+	cacheReleases(t, tool, rels)
+
+	// nothing in use
+	if current, target, err := UpgradeTarget(context.Background(), tool, github.RepoConfDef{}, utils.LevelPatch); err != nil || current != "" || target != "" {
+		t.Fatalf("expected nothing to upgrade, got %q -> %q, %v", current, target, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	installed := filepath.Join(vrsPath, tool, tool+"-v1.29.0")
+	if err := os.WriteFile(installed, []byte("x"), 0o755); err != nil {
+		t.Fatalf("failed to write binary: %v", err)
+	}
+	if err := os.MkdirAll(binPath, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.Symlink(installed, filepath.Join(binPath, tool)); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	o, err := GetOutdated(context.Background(), tool, github.RepoConfDef{})
+	if err != nil {
+		t.Fatalf("GetOutdated returned error: %v", err)
+	}
+	if o.Current != "v1.29.0" || o.Patch != "v1.29.3" || o.Minor != "v1.30.2" || o.Major != "v2.0.1" || o.CacheTimestamp == nil {
+		t.Fatalf("unexpected outdated report: %+v", o)
+	}
+
+	for level, want := range map[string]string{
+		utils.LevelPatch: "v1.29.3",
+		utils.LevelMinor: "v1.30.2",
+		utils.LevelMajor: "v2.0.1",
+	} {
+		if _, target, err := UpgradeTarget(context.Background(), tool, github.RepoConfDef{}, level); err != nil || target != want {
+			t.Fatalf("expected %s upgrade to %s, got %q, %v", level, want, target, err)
+		}
+	}
+
+	// a project pin caps the upgrades
+	if err := os.WriteFile(filepath.Join(td, project.FileName), []byte("tools:\n  uptool: \"~1.29\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	if _, target, err := UpgradeTarget(context.Background(), tool, github.RepoConfDef{}, utils.LevelMajor); err != nil || target != "v1.29.3" {
+		t.Fatalf("expected the pin to hold the upgrade at v1.29.3, got %q, %v", target, err)
+	}
+
+	// the lockfile caps them too, so that the upgrade is verified against it
+	lock := "tools:\n  uptool:\n    version: \"~1.29\"\n    tag: v1.29.0\n    platforms: {}\n"
+	if err := os.WriteFile(filepath.Join(td, project.LockFileName), []byte(lock), 0o644); err != nil {
+		t.Fatalf("failed to write lockfile: %v", err)
+	}
+	if current, target, err := UpgradeTarget(context.Background(), tool, github.RepoConfDef{}, utils.LevelMajor); err != nil || current != "v1.29.0" || target != "" {
+		t.Fatalf("expected the lock to hold the upgrade at v1.29.0, got %q -> %q, %v", current, target, err)
+	}
+}
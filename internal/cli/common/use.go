@@ -3,11 +3,13 @@ package common
 import (
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/shim"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
@@ -17,14 +19,19 @@ var (
 )
 
 // newUseCommand creates a new 'use' command for the specified tool
-func newUseCommand(tool string) *cobra.Command {
+func newUseCommand(tool string, repoConf github.RepoConfDef) *cobra.Command {
 	useCmd := &cobra.Command{
 		Use:   "use <version>",
 		Short: fmt.Sprintf("Set the specified %s version as the active one", tool),
-		Long:  fmt.Sprintf(`Create a symlink to the specified version with the name "%s".\n\nMake sure the "bin-path" is included in the $PATH variable.`, tool),
-		Args:  cobra.ExactArgs(1),
+		Long: fmt.Sprintf("Create a symlink to the specified version with the name \"%s\".\n\n"+
+			"In shim mode (\"mode: shim\"), a dispatcher named \"%s\" is created instead and the version becomes the global default, "+
+			"which can be overridden per shell with %s or per project with %s.\n\n"+
+			"The version may also be an alias (\"latest\" or \"stable\", \"prerelease\" to include pre-releases), a partial version or a semver constraint, "+
+			"resolved against the installed versions.\n\n"+
+			"Make sure the \"bin-path\" is included in the $PATH variable.", tool, tool, shim.EnvVar(tool), project.FileName),
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return use(cmd, args[0], tool)
+			return use(cmd, args[0], tool, repoConf)
 		},
 	}
 	// Bind flags to Viper keys so config file / env / flags work together.
@@ -36,8 +43,23 @@ func newUseCommand(tool string) *cobra.Command {
 	return useCmd
 }
 
-// use sets the specified version of the tool as the active one
-func use(cmd *cobra.Command, vrs, tool string) error {
+// UseOptions tunes UseVersion as the flags of the use command do.
+type UseOptions struct {
+	// Install installs the version first when it is not, best effort.
+	Install bool
+	// SkipVerify skips the SHA-256 verification of the version installed (unsafe).
+	SkipVerify bool
+}
+
+// use sets the specified version of the tool as the active one, with the options set for it
+func use(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef) error {
+	installOnUse = viper.GetBool(tool + ".use.install")
+	return UseVersion(cmd, vrs, tool, repoConf, UseOptions{Install: installOnUse, SkipVerify: viper.GetBool(tool + ".install.skip-verify")})
+}
+
+// UseVersion sets the version of the tool released from repoConf as the active one as `vrsr <tool> use` does,
+// with the given options instead of the ones set for the tool.
+func UseVersion(cmd *cobra.Command, vrs, tool string, repoConf github.RepoConfDef, opts UseOptions) error {
 	binPath := viper.GetString("bin-path")
 	err := utils.EnsurePathExists(binPath)
 	if err != nil {
@@ -45,42 +67,67 @@ func use(cmd *cobra.Command, vrs, tool string) error {
 		return err
 	}
 	vrsPath := viper.GetString("vrs-path")
-	fileName := filepath.Join(vrsPath, tool, tool+"-"+vrs)
-	if _, err := os.Stat(fileName); errors.Is(err, os.ErrNotExist) {
-		installOnUse = viper.GetBool(tool + ".use.install")
-		if !installOnUse {
+	// best effort, as the vrs path may be read-only: versions are found without their manifests written
+	if unlock, err := lockInstalled(cmd, vrsPath, tool); err == nil {
+		unlock()
+	}
+	resolved, err := utils.ResolveInstalled(vrsPath, tool, vrs)
+	if err != nil {
+		if !opts.Install {
 			cmd.Printf("Error: specified version is not installed. Please install it first using `vrsr %s install <version>`", tool)
 			return errVrsNotFound
 		}
-		pCmd := cmd.Parent()
-		if pCmd == nil {
-			return fmt.Errorf("internal error: use command has no parent")
-		}
-		iCmd, _, err := pCmd.Find([]string{"install"})
-		if err != nil || iCmd.Name() != "install" {
-			return fmt.Errorf("could not find sibling 'install' command")
-		}
-		if err := iCmd.RunE(iCmd, []string{vrs, "true"}); err != nil {
+		if _, err := InstallVersion(cmd, vrs, tool, repoConf, InstallOptions{SkipVerify: opts.SkipVerify, Quiet: true}); err != nil {
 			cmd.Println("Error executing install:", err)
 			cmd.Println("Skipping action")
 			return err
 		}
-		// here we should have installed the version, we assume it succeeded
+		// here we should have installed the version, look it up again
+		resolved, err = utils.ResolveInstalled(vrsPath, tool, vrs)
+		if err != nil {
+			return err
+		}
 	}
-	target := filepath.Join(binPath, tool)
-	// Check if the symlink already exists
-	if _, err := os.Lstat(target); err == nil {
-		// Remove existing symlink or file
-		if err := os.Remove(target); err != nil {
-			cmd.Println("Error removing existing symlink:", err)
+	if resolved != vrs {
+		cmd.Printf("Resolved %s to %s version %s\n", vrs, tool, resolved)
+		vrs = resolved
+	}
+	fileName, err := utils.GetBinaryPath(vrsPath, tool, vrs)
+	if err != nil {
+		cmd.Println("Error reading version manifest:", err)
+		return err
+	}
+	unlock, err := utils.LockInUse(commandContext(cmd), cmd.ErrOrStderr(), binPath, tool)
+	if err != nil {
+		cmd.Println("Error locking bin path:", err)
+		return err
+	}
+	defer unlock()
+	if viper.GetString("mode") == shim.ModeShim {
+		// the shim picks the version at exec time, we only record the global one
+		if err := utils.WriteGlobalVersion(binPath, tool, vrs); err != nil {
+			cmd.Println("Error saving global version:", err)
 			return err
 		}
+		if !utils.IsShim(filepath.Join(binPath, tool)) {
+			if err := shim.Install(binPath, vrsPath, tool); err != nil {
+				cmd.Println("Error creating shim:", err)
+				return err
+			}
+		}
+		cmd.Printf("Now using %s version %s (global, overridable with %s or %s)\n", tool, vrs, shim.EnvVar(tool), project.FileName)
+		return nil
 	}
-	// create new symlink
-	if err := os.Symlink(fileName, target); err != nil {
+
+	// swap the symlink (or shim) for the new one, the tool is never missing from the bin path
+	if err := utils.SymlinkAtomic(fileName, filepath.Join(binPath, tool)); err != nil {
 		cmd.Println("Error creating symlink:", err)
 		return err
 	}
+	// the global version of shim mode is now stale
+	if err := utils.RemoveGlobalVersion(binPath, tool); err != nil {
+		cmd.Println("Error removing global version file:", err)
+	}
 
 	cmd.Printf("Now using %s version %s\n", tool, vrs)
 	return nil
@@ -1,13 +1,18 @@
 package common
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 func TestUseCommand_ArgsValidation(t *testing.T) {
@@ -53,7 +58,7 @@ func TestUse_CreatesSymlink(t *testing.T) {
 	viper.Set("vrs-path", vrsPath)
 	viper.Set("bin-path", binPath)
 
-	if err := use(&cobra.Command{}, version, tool); err != nil {
+	if err := use(&cobra.Command{}, version, tool, github.RepoConfDef{}); err != nil {
 		t.Fatalf("use failed: %v", err)
 	}
 
@@ -67,3 +72,156 @@ func TestUse_CreatesSymlink(t *testing.T) {
 		t.Fatalf("symlink target mismatch: expected %s got %s", filePath, target)
 	}
 }
+
+func TestUse_ShimModeRecordsGlobalVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shims are not supported on windows")
+	}
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "shimmed"
+	version := "v0.1.0"
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create vrs dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vrsPath, tool, tool+"-"+version), []byte("x"), 0o755); err != nil {
+		t.Fatalf("failed to create vrs file: %v", err)
+	}
+
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	viper.Set("mode", "shim")
+	defer viper.Set("mode", "symlink")
+
+	if err := use(&cobra.Command{}, version, tool, github.RepoConfDef{}); err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+	if !utils.IsShim(filepath.Join(binPath, tool)) {
+		t.Fatalf("expected a shim in the bin path")
+	}
+	if got, err := utils.GetVrsInUse(binPath, tool); err != nil || got != version {
+		t.Fatalf("expected global version %s, got %s (%v)", version, got, err)
+	}
+}
+
+func TestUse_ResolvesConstraintsAgainstInstalledVersions(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "constool"
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create vrs dir: %v", err)
+	}
+	for _, v := range []string{"v1.29.1", "v1.30.2", "v1.30.5", "v1.31.0-rc.1"} {
+		if err := os.WriteFile(filepath.Join(vrsPath, tool, tool+"-"+v), []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to create vrs file: %v", err)
+		}
+	}
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+
+	for input, want := range map[string]string{
+		"1.30":           "v1.30.5",
+		"~1.29.0":        "v1.29.1",
+		"1.30.2":         "v1.30.2",
+		"stable":         "v1.30.5",
+		"latest":         "v1.30.5",
+		"prerelease":     "v1.31.0-rc.1",
+		">=1.30 <1.30.5": "v1.30.2",
+	} {
+		cmd := &cobra.Command{}
+		var sb strings.Builder
+		cmd.SetOut(&sb)
+		if err := use(cmd, input, tool, github.RepoConfDef{}); err != nil {
+			t.Fatalf("use %q failed: %v", input, err)
+		}
+		if got, _ := utils.GetVrsInUse(binPath, tool); got != want {
+			t.Fatalf("expected %q to resolve to %s, got %s", input, want, got)
+		}
+		if !strings.Contains(sb.String(), "Now using "+tool+" version "+want) {
+			t.Fatalf("expected concrete version to be printed, got: %s", sb.String())
+		}
+	}
+
+	viper.Set(tool+".use.install", false)
+	if err := use(&cobra.Command{}, "2.x", tool, github.RepoConfDef{}); err == nil {
+		t.Fatalf("expected error when no installed version matches")
+	}
+}
+
+func TestUse_SwapsSymlinkWithoutGap(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	binPath := filepath.Join(td, "bin")
+	tool := "swaptool"
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create vrs dir: %v", err)
+	}
+	versions := []string{"v1.0.0", "v2.0.0"}
+	for _, v := range versions {
+		if err := os.WriteFile(filepath.Join(vrsPath, tool, tool+"-"+v), []byte("x"), 0o755); err != nil {
+			t.Fatalf("failed to create vrs file: %v", err)
+		}
+	}
+	viper.Set("vrs-path", vrsPath)
+	viper.Set("bin-path", binPath)
+	if err := use(&cobra.Command{}, versions[0], tool, github.RepoConfDef{}); err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+
+	link := filepath.Join(binPath, tool)
+	done := make(chan struct{})
+	missing := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				close(missing)
+				return
+			default:
+			}
+			if _, err := os.Lstat(link); err != nil {
+				missing <- err
+				close(missing)
+				return
+			}
+		}
+	}()
+	// two processes switching at once take turns
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for _, v := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := &cobra.Command{}
+			cmd.SetOut(io.Discard)
+			for range 20 {
+				if err := use(cmd, v, tool, github.RepoConfDef{}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	close(errs)
+	for err := range errs {
+		t.Fatalf("use failed: %v", err)
+	}
+	if err := <-missing; err != nil {
+		t.Fatalf("the tool went missing from the bin path: %v", err)
+	}
+
+	entries, err := os.ReadDir(binPath)
+	if err != nil {
+		t.Fatalf("failed to read bin path: %v", err)
+	}
+	for _, e := range entries {
+		if e.Name() != tool && e.Name() != ".locks" && e.Name() != ".global" {
+			t.Fatalf("unexpected leftover %s in the bin path", e.Name())
+		}
+	}
+}
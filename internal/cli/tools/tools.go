@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/cli/common"
+	"github.com/stepbeta/vrsr/internal/github"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// ToolDef declares a tool managed by vrsr.
+// Tools are declared in the "tools" key of the config file or in drop-in files
+// under ~/.vrsr/tools.d, e.g.:
+//
+//	tools:
+//	  k9s:
+//	    org: derailed
+//	    repo: k9s
+//	    asset-pattern: "k9s_{{.OS | title}}_{{.Arch}}.tar.gz"
+//	    archive-type: tar.gz
+//	    github:
+//	      base-url: https://github.example.com/api/v3/
+type ToolDef struct {
+	Name          string `mapstructure:"-"`
+	Description   string `mapstructure:"description"`
+	Org           string `mapstructure:"org"`
+	Repo          string `mapstructure:"repo"`
+	DownloadURL   string `mapstructure:"download-url"`
+	AssetPattern  string `mapstructure:"asset-pattern"`
+	ArchiveType   string `mapstructure:"archive-type"`
+	BinaryPath    string `mapstructure:"binary-path"`
+	ChecksumURL   string `mapstructure:"checksum-url"`
+	ChecksumAsset string `mapstructure:"checksum-asset"`
+	// GitHub points at the GitHub Enterprise instance hosting the repository.
+	GitHub GitHubDef `mapstructure:"github"`
+}
+
+// GitHubDef holds the GitHub API endpoints of a tool.
+type GitHubDef struct {
+	BaseURL   string `mapstructure:"base-url"`
+	UploadURL string `mapstructure:"upload-url"`
+}
+
+// builtinTools are the tools available without any configuration.
+var builtinTools = map[string]ToolDef{
+	"kind": {
+		Org:           "kubernetes-sigs",
+		Repo:          "kind",
+		ChecksumAsset: "kind-{{.OS}}-{{.Arch}}.sha256sum",
+	},
+	"talosctl": {
+		Org:           "siderolabs",
+		Repo:          "talos",
+		ChecksumAsset: "sha256sum.txt",
+	},
+	"kubectl": {
+		Org:  "kubernetes",
+		Repo: "kubernetes",
+		// Example: "https://dl.k8s.io/release/v1.35.0/bin/linux/amd64/kubectl"
+		DownloadURL: "https://dl.k8s.io/release/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl",
+		ChecksumURL: "https://dl.k8s.io/release/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl.sha256",
+	},
+	"helm": {
+		Org:  "helm",
+		Repo: "helm",
+		// Example: "https://get.helm.sh/helm-v4.0.0-linux-amd64.tar.gz"
+		DownloadURL: "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz",
+		ArchiveType: utils.ArchiveTarGz,
+		BinaryPath:  "{{.OS}}-{{.Arch}}/helm",
+		ChecksumURL: "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz.sha256sum",
+	},
+}
+
+// RepoConf returns the release configuration of the tool.
+func (td ToolDef) RepoConf() github.RepoConfDef {
+	return github.RepoConfDef{
+		Org:           td.Org,
+		Repo:          td.Repo,
+		DownloadURL:   td.DownloadURL,
+		AssetPattern:  td.AssetPattern,
+		ArchiveType:   td.ArchiveType,
+		BinaryPath:    td.BinaryPath,
+		ChecksumURL:   td.ChecksumURL,
+		ChecksumAsset: td.ChecksumAsset,
+		BaseURL:       td.GitHub.BaseURL,
+		UploadURL:     td.GitHub.UploadURL,
+	}
+}
+
+// LoadToolDefs returns the built-in tools merged with the ones declared in the
+// drop-in directory and in the config file (in that order of precedence, last wins).
+// Invalid definitions are reported in the returned warnings and skipped.
+func LoadToolDefs(cfgFile string) ([]ToolDef, []error) {
+	defs := make(map[string]ToolDef, len(builtinTools))
+	for name, td := range builtinTools {
+		defs[name] = td
+	}
+	var warnings []error
+
+	if dir, err := utils.GetToolsDefPath(); err == nil {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.y*ml"))
+		sort.Strings(files)
+		for _, f := range files {
+			v := viper.New()
+			v.SetConfigFile(f)
+			if err := mergeToolDefs(v, defs); err != nil {
+				warnings = append(warnings, fmt.Errorf("%s: %w", f, err))
+			}
+		}
+	}
+
+	v := viper.New()
+	if err := utils.SetConfigSource(v, cfgFile); err != nil {
+		warnings = append(warnings, err)
+	} else if err := mergeToolDefs(v, defs); err != nil && !isConfigNotFound(err) {
+		warnings = append(warnings, fmt.Errorf("config: %w", err))
+	}
+
+	res := make([]ToolDef, 0, len(defs))
+	for name, td := range defs {
+		td.Name = name
+		if err := td.RepoConf().Validate(); err != nil {
+			warnings = append(warnings, fmt.Errorf("tool %q: %w", name, err))
+			continue
+		}
+		res = append(res, td)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, warnings
+}
+
+// mergeToolDefs reads the "tools" key from the config source of v into defs.
+func mergeToolDefs(v *viper.Viper, defs map[string]ToolDef) error {
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	var declared map[string]ToolDef
+	if err := v.UnmarshalKey("tools", &declared); err != nil {
+		return err
+	}
+	for name, td := range declared {
+		defs[strings.ToLower(name)] = td
+	}
+	return nil
+}
+
+// isConfigNotFound reports whether err means no config file was found, which is fine.
+func isConfigNotFound(err error) bool {
+	var configFileNotFoundError viper.ConfigFileNotFoundError
+	return errors.As(err, &configFileNotFoundError)
+}
+
+// NewToolCommand creates the command managing the versions of the given tool.
+func NewToolCommand(td ToolDef) *cobra.Command {
+	long := td.Description
+	if long == "" {
+		long = fmt.Sprintf("A tool to easily install and use multiple versions of %s.", td.Name)
+	}
+	cmd := &cobra.Command{
+		Use:   td.Name,
+		Short: fmt.Sprintf("Manage %s versions", td.Name),
+		Long:  long,
+	}
+	common.InitCommand(cmd, td.Name, td.RepoConf())
+	return cmd
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadToolDefs_MergesDropInsAndConfig(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+
+	dropIns := filepath.Join(td, ".vrsr", "tools.d")
+	if err := os.MkdirAll(dropIns, 0o755); err != nil {
+		t.Fatalf("failed to create tools.d: %v", err)
+	}
+	dropIn := `tools:
+  k9s:
+    org: derailed
+    repo: k9s
+    asset-pattern: "k9s_{{.OS | title}}_{{.Arch}}.tar.gz"
+    archive-type: tar.gz
+  broken:
+    org: nope
+`
+	if err := os.WriteFile(filepath.Join(dropIns, "k9s.yaml"), []byte(dropIn), 0o644); err != nil {
+		t.Fatalf("failed to write drop-in: %v", err)
+	}
+	cfg := `tools:
+  k9s:
+    org: derailed
+    repo: k9s
+    description: overridden
+`
+	cfgFile := filepath.Join(td, "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte(cfg), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	defs, warnings := LoadToolDefs(cfgFile)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning for the broken definition, got %v", warnings)
+	}
+	byName := map[string]ToolDef{}
+	for _, d := range defs {
+		byName[d.Name] = d
+	}
+	for name := range builtinTools {
+		if _, ok := byName[name]; !ok {
+			t.Fatalf("expected built-in tool %s to be loaded", name)
+		}
+	}
+	k9s, ok := byName["k9s"]
+	if !ok {
+		t.Fatalf("expected k9s to be loaded, got %v", defs)
+	}
+	if k9s.Description != "overridden" || k9s.ArchiveType != "" {
+		t.Fatalf("expected config file definition to win over drop-in, got %+v", k9s)
+	}
+	if _, ok := byName["broken"]; ok {
+		t.Fatalf("expected broken definition to be skipped")
+	}
+}
+
+func TestLoadToolDefs_InvalidTemplate(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	cfg := `tools:
+  weird:
+    org: o
+    repo: r
+    download-url: "https://example.com/{{.Nope"
+`
+	cfgFile := filepath.Join(td, "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte(cfg), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	defs, warnings := LoadToolDefs(cfgFile)
+	if len(warnings) != 1 {
+		t.Fatalf("expected a warning for the invalid template, got %v", warnings)
+	}
+	for _, d := range defs {
+		if d.Name == "weird" {
+			t.Fatalf("expected invalid definition to be skipped")
+		}
+	}
+}
+
+func TestLoadToolDefs_GitHubEnterprise(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	cfg := `tools:
+  internal-cli:
+    org: platform
+    repo: internal-cli
+    github:
+      base-url: https://github.example.com/api/v3/
+  bad-host:
+    org: o
+    repo: r
+    github:
+      base-url: github.example.com
+`
+	cfgFile := filepath.Join(td, "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte(cfg), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	defs, warnings := LoadToolDefs(cfgFile)
+	if len(warnings) != 1 {
+		t.Fatalf("expected a warning for the invalid base URL, got %v", warnings)
+	}
+	for _, d := range defs {
+		switch d.Name {
+		case "internal-cli":
+			if d.RepoConf().BaseURL != "https://github.example.com/api/v3/" {
+				t.Fatalf("expected the base URL to be loaded, got %+v", d.RepoConf())
+			}
+		case "bad-host":
+			t.Fatalf("expected invalid definition to be skipped")
+		}
+	}
+}
+
+func TestNewToolCommand_RegistersSubcommands(t *testing.T) {
+	cmd := NewToolCommand(ToolDef{Name: "sometool", Org: "o", Repo: "r"})
+	if cmd.Use != "sometool" {
+		t.Fatalf("unexpected command name %q", cmd.Use)
+	}
+	if len(cmd.Commands()) == 0 {
+		t.Fatalf("expected common subcommands to be registered")
+	}
+}
@@ -0,0 +1,60 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v78/github"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// validatorsKey is the context key of the validators of a conditional request.
+type validatorsKey struct{}
+
+// validators are the ETag and Last-Modified values of a previous response.
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// withValidators returns a context making the API request conditional on the given ETag and
+// Last-Modified values: GitHub answers 304 Not Modified, which does not count against the rate limit,
+// when the resource did not change.
+func withValidators(ctx context.Context, etag, lastModified string) context.Context {
+	return context.WithValue(ctx, validatorsKey{}, validators{etag: etag, lastModified: lastModified})
+}
+
+// conditionalTransport adds the validators of the request context, if any, to the request headers.
+type conditionalTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends the request, conditionally when its context carries validators.
+func (t conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if v, ok := req.Context().Value(validatorsKey{}).(validators); ok {
+		req = req.Clone(req.Context())
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// newClient returns a GitHub client able to send conditional requests.
+func newClient() *github.Client {
+	return github.NewClient(&http.Client{Transport: conditionalTransport{base: utils.Transport()}})
+}
+
+// isNotModified reports whether err is the 304 Not Modified answer to a conditional request.
+func isNotModified(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotModified
+}
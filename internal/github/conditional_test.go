@@ -0,0 +1,69 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestFetchAllReleases_ConditionalAndIncremental(t *testing.T) {
+	isolateCredentials(t)
+	tags := []string{"v1.1.0", "v1.0.0"}
+	etag := `"v1"`
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		var rels []map[string]string
+		for _, tag := range tags {
+			rels = append(rels, map[string]string{"tag_name": tag})
+		}
+		_ = json.NewEncoder(w).Encode(rels)
+	}))
+	defer srv.Close()
+	repo := RepoConfDef{Org: "o", Repo: "r", BaseURL: srv.URL + "/api/v3/"}
+	ghh, err := NewFor(repo)
+	if err != nil {
+		t.Fatalf("NewFor returned error: %v", err)
+	}
+	tool := "condtool"
+
+	if _, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true, RepoConf: repo}); err != nil {
+		t.Fatalf("FetchAllReleases returned error: %v", err)
+	}
+	if cached, err := utils.ReadFromCache(tool, 0); err != nil || cached.ETag != etag {
+		t.Fatalf("expected the ETag to be cached, got %+v (%v)", cached, err)
+	}
+
+	// unchanged list
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true, RepoConf: repo})
+	if err != nil || len(data.Releases) != 2 || notModified != 1 {
+		t.Fatalf("expected a 304 answered from the cache, got %d releases, %d 304s (%v)", len(data.Releases), notModified, err)
+	}
+
+	// a new release appeared, the list changed
+	tags, etag = append([]string{"v1.2.0"}, tags...), `"v2"`
+	data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true, Limit: 2, RepoConf: repo})
+	if err != nil {
+		t.Fatalf("FetchAllReleases returned error: %v", err)
+	}
+	if len(data.Releases) != 2 || data.Releases[0].Tag != "v1.2.0" {
+		t.Fatalf("expected the limited merged releases, got %d", len(data.Releases))
+	}
+	cached, err := utils.ReadFromCache(tool, 0)
+	if err != nil || len(cached.Releases) != 3 || cached.ETag != etag {
+		t.Fatalf("expected the merged releases to be cached with the new ETag, got %+v (%v)", cached, err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected one request per refresh, got %d", requests)
+	}
+}
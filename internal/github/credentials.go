@@ -0,0 +1,281 @@
+package github
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+	"go.yaml.in/yaml/v3"
+)
+
+// Credential is a GitHub token along with where it was found.
+type Credential struct {
+	Token string
+	// Source describes where the token comes from, e.g. "GITHUB_TOKEN".
+	Source string
+}
+
+// credentialsFile is the content of the file written by `vrsr auth login`.
+type credentialsFile struct {
+	Hosts map[string]hostCredentials `yaml:"hosts"`
+}
+
+// hostCredentials are the credentials of a host in the credentials file.
+type hostCredentials struct {
+	Token string `yaml:"token"`
+}
+
+// credentialSource looks up the token of a host.
+type credentialSource struct {
+	name   string
+	lookup func(host string) string
+}
+
+// publicEnvTokens are the environment variables holding a token for github.com,
+// enterpriseEnvTokens the ones holding a token for any other host.
+var (
+	publicEnvTokens     = []string{"VRSR_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"}
+	enterpriseEnvTokens = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+)
+
+// FindCredential returns the first token found for the GitHub host along the credential chain:
+//
+//  1. the "github.tokens" setting;
+//  2. the VRSR_GITHUB_TOKEN, GITHUB_TOKEN and GH_TOKEN environment variables for github.com,
+//     GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN for other hosts;
+//  3. the credentials file written by `vrsr auth login`;
+//  4. the hosts.yml file of the gh CLI;
+//  5. the netrc file.
+//
+// Unreadable files are skipped. ok is false when no token is found.
+func FindCredential(host string) (Credential, bool) {
+	host = strings.ToLower(host)
+	for _, src := range credentialSources(host) {
+		if token := strings.TrimSpace(src.lookup(host)); token != "" {
+			return Credential{Token: token, Source: src.name}, true
+		}
+	}
+	return Credential{}, false
+}
+
+// Token returns the token to authenticate against the GitHub host, empty when there is none.
+// Tokens are never sent to a host they are not meant for.
+func Token(host string) string {
+	c, _ := FindCredential(host)
+	return c.Token
+}
+
+// credentialSources returns the credential chain for the host, in order.
+func credentialSources(host string) []credentialSource {
+	sources := []credentialSource{{
+		name: "config (github.tokens)",
+		lookup: func(host string) string {
+			return viper.GetStringMapString("github.tokens")[host]
+		},
+	}}
+	envs := enterpriseEnvTokens
+	if host == publicHost {
+		envs = publicEnvTokens
+	}
+	for _, env := range envs {
+		sources = append(sources, credentialSource{name: env, lookup: func(string) string { return os.Getenv(env) }})
+	}
+	if p, err := utils.GetCredentialsPath(); err == nil {
+		sources = append(sources, credentialSource{name: p, lookup: func(host string) string { return credentialsFileToken(p, host) }})
+	}
+	if p := ghHostsPath(); p != "" {
+		sources = append(sources, credentialSource{name: "gh CLI (" + p + ")", lookup: func(host string) string { return ghHostsToken(p, host) }})
+	}
+	if p := netrcPath(); p != "" {
+		sources = append(sources, credentialSource{name: p, lookup: func(host string) string { return netrcToken(p, host) }})
+	}
+	return sources
+}
+
+// credentialsFileToken returns the token saved by `vrsr auth login` for the host.
+func credentialsFileToken(p, host string) string {
+	creds, err := readCredentialsFile(p)
+	if err != nil {
+		return ""
+	}
+	return creds.Hosts[host].Token
+}
+
+// readCredentialsFile reads the credentials file at p, which may not exist.
+func readCredentialsFile(p string) (credentialsFile, error) {
+	var creds credentialsFile
+	content, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+	if err := yaml.Unmarshal(content, &creds); err != nil {
+		return creds, fmt.Errorf("invalid credentials file %s: %w", p, err)
+	}
+	return creds, nil
+}
+
+// SaveToken stores the token of the host into the credentials file, readable by the current user only,
+// and returns the path of the file.
+func SaveToken(host, token string) (string, error) {
+	p, err := utils.GetCredentialsPath()
+	if err != nil {
+		return "", err
+	}
+	creds, err := readCredentialsFile(p)
+	if err != nil {
+		return "", err
+	}
+	if creds.Hosts == nil {
+		creds.Hosts = map[string]hostCredentials{}
+	}
+	creds.Hosts[strings.ToLower(host)] = hostCredentials{Token: token}
+	content, err := yaml.Marshal(creds)
+	if err != nil {
+		return "", err
+	}
+	return p, utils.WriteFileAtomic(p, content, 0600)
+}
+
+// ghHostsPath returns the path of the hosts.yml file of the gh CLI.
+func ghHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "gh", "hosts.yml")
+}
+
+// ghHostsToken returns the token of the host in the gh CLI hosts.yml file at p.
+// Tokens kept by gh in the system keyring are not available.
+func ghHostsToken(p, host string) string {
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(content, &hosts); err != nil {
+		return ""
+	}
+	for h, entry := range hosts {
+		if strings.EqualFold(h, host) {
+			return entry.OAuthToken
+		}
+	}
+	return ""
+}
+
+// netrcPath returns the path of the netrc file.
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(homeDir, "_netrc")
+	}
+	return filepath.Join(homeDir, ".netrc")
+}
+
+// netrcToken returns the password of the host, or of its API host, in the netrc file at p.
+// The "default" entry is ignored, so that its password is never sent to GitHub.
+func netrcToken(p, host string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	machine := ""
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "machine":
+			if !scanner.Scan() {
+				return ""
+			}
+			machine = strings.ToLower(scanner.Text())
+		case "default":
+			machine = ""
+		case "macdef":
+			// macro definitions run until an empty line, which ScanWords cannot see
+			return ""
+		case "password":
+			if !scanner.Scan() {
+				return ""
+			}
+			if machine == host || machine == "api."+host {
+				return scanner.Text()
+			}
+		}
+	}
+	return ""
+}
+
+// WithToken returns a copy of the helper authenticated with the given token.
+func (gh *GithubHelper) WithToken(token string) GithubHelper {
+	client := gh.Client.WithAuthToken(token)
+	return GithubHelper{Client: client, Repos: client.Repositories, authenticated: token != ""}
+}
+
+// Login returns the login of the user the helper is authenticated as.
+func (gh *GithubHelper) Login(ctx context.Context) (string, error) {
+	user, _, err := gh.Client.Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// RateLimit is the state of the core API rate limit.
+type RateLimit struct {
+	Limit     int       `json:"limit" yaml:"limit"`
+	Remaining int       `json:"remaining" yaml:"remaining"`
+	Reset     time.Time `json:"reset" yaml:"reset"`
+}
+
+// RateLimit returns the core API rate limit of the helper, nil when the host does not rate limit
+// (e.g. a GitHub Enterprise instance with rate limiting disabled).
+func (gh *GithubHelper) RateLimit(ctx context.Context) (*RateLimit, error) {
+	limits, _, err := gh.Client.RateLimit.Get(ctx)
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	core := limits.GetCore()
+	if core == nil {
+		return nil, nil
+	}
+	return &RateLimit{Limit: core.Limit, Remaining: core.Remaining, Reset: core.Reset.UTC()}, nil
+}
@@ -0,0 +1,135 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// isolateCredentials clears every credential source of the environment
+func isolateCredentials(t *testing.T) string {
+	t.Helper()
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	for _, env := range append(append([]string{"GH_CONFIG_DIR", "XDG_CONFIG_HOME", "NETRC"}, publicEnvTokens...), enterpriseEnvTokens...) {
+		t.Setenv(env, "")
+	}
+	viper.Set("github.tokens", map[string]string{})
+	return td
+}
+
+func TestFindCredential_Chain(t *testing.T) {
+	td := isolateCredentials(t)
+	ghe := "github.example.com"
+
+	if _, ok := FindCredential(publicHost); ok {
+		t.Fatalf("expected no credential")
+	}
+
+	netrc := "default login me password default-secret\n" +
+		"machine api.github.com login me password netrc-token\n" +
+		"machine github.example.com\n  login me\n  password netrc-ghe-token\n"
+	if err := os.WriteFile(filepath.Join(td, ".netrc"), []byte(netrc), 0o600); err != nil {
+		t.Fatalf("failed to write netrc: %v", err)
+	}
+	ghDir := filepath.Join(td, ".config", "gh")
+	if err := os.MkdirAll(ghDir, 0o755); err != nil {
+		t.Fatalf("failed to create gh config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ghDir, "hosts.yml"), []byte("github.com:\n  user: me\n  oauth_token: gh-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write gh hosts: %v", err)
+	}
+
+	expect := func(host, token, source string) {
+		t.Helper()
+		c, ok := FindCredential(host)
+		if !ok || c.Token != token || (source != "" && c.Source != source) {
+			t.Fatalf("expected %s token %q from %q, got %+v", host, token, source, c)
+		}
+	}
+	expect(ghe, "netrc-ghe-token", filepath.Join(td, ".netrc"))
+	if _, ok := FindCredential("other.example.com"); ok {
+		t.Fatalf("expected the default netrc entry to be ignored")
+	}
+	expect(publicHost, "gh-token", "")
+
+	// an existing file readable by others is replaced rather than written in place
+	credsPath, err := utils.GetCredentialsPath()
+	if err != nil {
+		t.Fatalf("GetCredentialsPath returned error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(credsPath), 0755); err != nil {
+		t.Fatalf("failed to create the credentials dir: %v", err)
+	}
+	if err := os.WriteFile(credsPath, []byte("hosts: {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write the credentials file: %v", err)
+	}
+	p, err := SaveToken(publicHost, "login-token")
+	if err != nil {
+		t.Fatalf("SaveToken returned error: %v", err)
+	}
+	if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected credentials file readable by the user only, got %v (%v)", fi.Mode(), err)
+	}
+	expect(publicHost, "login-token", p)
+
+	t.Setenv("GH_TOKEN", "gh-env-token")
+	expect(publicHost, "gh-env-token", "GH_TOKEN")
+	t.Setenv("GITHUB_TOKEN", "github-env-token")
+	expect(publicHost, "github-env-token", "GITHUB_TOKEN")
+	t.Setenv("VRSR_GITHUB_TOKEN", "vrsr-env-token")
+	expect(publicHost, "vrsr-env-token", "VRSR_GITHUB_TOKEN")
+	// the github.com variables are not sent to other hosts
+	expect(ghe, "netrc-ghe-token", "")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghe-env-token")
+	expect(ghe, "ghe-env-token", "GH_ENTERPRISE_TOKEN")
+
+	viper.Set("github.tokens", map[string]string{publicHost: "config-token"})
+	defer viper.Set("github.tokens", map[string]string{})
+	expect(publicHost, "config-token", "config (github.tokens)")
+}
+
+func TestRateLimitAndLogin(t *testing.T) {
+	isolateCredentials(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/rate_limit" && r.Header.Get("Authorization") == "Bearer good":
+			_, _ = w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1700000000}}}`))
+		case r.URL.Path == "/api/v3/user" && r.Header.Get("Authorization") == "Bearer good":
+			_, _ = w.Write([]byte(`{"login": "me"}`))
+		case r.URL.Path == "/api/v3/user":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+		default:
+			// rate limiting disabled
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ghh, err := NewFor(RepoConfDef{BaseURL: srv.URL + "/api/v3/"})
+	if err != nil {
+		t.Fatalf("NewFor returned error: %v", err)
+	}
+
+	if rl, err := ghh.RateLimit(context.Background()); err != nil || rl != nil {
+		t.Fatalf("expected no rate limit, got %+v (%v)", rl, err)
+	}
+	good := ghh.WithToken("good")
+	rl, err := good.RateLimit(context.Background())
+	if err != nil || rl == nil || rl.Limit != 5000 || rl.Remaining != 4990 {
+		t.Fatalf("unexpected rate limit %+v (%v)", rl, err)
+	}
+	if login, err := good.Login(context.Background()); err != nil || login != "me" {
+		t.Fatalf("expected login me, got %q (%v)", login, err)
+	}
+	bad := ghh.WithToken("bad")
+	if _, err := bad.Login(context.Background()); err == nil {
+		t.Fatalf("expected bad token to be rejected")
+	}
+}
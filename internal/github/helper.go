@@ -7,21 +7,26 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v78/github"
 	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/mirror"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
 var errReleaseNotFound = errors.New("release not found")
 
+// partialMaxAge is how long the progress of an interrupted fetch of the releases is resumed from.
+const partialMaxAge = 24 * time.Hour
+
 type GithubHelper struct {
 	Client *github.Client
 	Repos  repositoriesService
+	// authenticated is set when the client sends a token
+	authenticated bool
 }
 
 // repositoriesService defines the subset of github repository methods used by this helper.
@@ -31,25 +36,45 @@ type repositoriesService interface {
 	DownloadReleaseAsset(ctx context.Context, owner, repo string, assetID int64, httpClient *http.Client) (io.ReadCloser, string, error)
 }
 
+// New returns a helper using the given client (github.com when nil), authenticated with
+// the token of its host, if any.
 func New(client *github.Client) GithubHelper {
 	if client == nil {
-		client = github.NewClient(nil)
+		client = newClient()
 	}
 	// Optional: Use token for higher rate limits:
 	// - anonymous: 60 calls per hour
 	// - authenticated: 5,000 calls per hour
-	token := os.Getenv("GITHUB_TOKEN")
+	token := Token(Host(client.BaseURL))
 	if token != "" {
 		client = client.WithAuthToken(token)
 	}
-	return GithubHelper{Client: client, Repos: client.Repositories}
+	return GithubHelper{Client: client, Repos: client.Repositories, authenticated: token != ""}
 }
 
-type RepoConfDef struct {
-	Org         string
-	Repo        string
-	Zipped      bool
-	DownloadURL string
+// NewFor returns a helper targeting the GitHub instance hosting the repository.
+func NewFor(repo RepoConfDef) (GithubHelper, error) {
+	baseURL, uploadURL := repo.BaseURL, repo.UploadURL
+	if baseURL == "" {
+		baseURL, uploadURL = viper.GetString("github.base-url"), viper.GetString("github.upload-url")
+	}
+	for _, u := range []string{baseURL, uploadURL} {
+		if err := validateAPIURL(u); err != nil {
+			return GithubHelper{}, err
+		}
+	}
+	client := newClient()
+	if baseURL != "" {
+		if uploadURL == "" {
+			// nothing is uploaded, the upload endpoint only needs to be well formed
+			uploadURL = baseURL
+		}
+		var err error
+		if client, err = client.WithEnterpriseURLs(baseURL, uploadURL); err != nil {
+			return GithubHelper{}, err
+		}
+	}
+	return New(client), nil
 }
 
 type FetchOptions struct {
@@ -59,83 +84,340 @@ type FetchOptions struct {
 	RepoConf     RepoConfDef
 }
 
-// FetchAllReleases fetches all releases from the GitHub repository
-func (gh *GithubHelper) FetchAllReleases(tool string, opts FetchOptions) (utils.ReleasesData, error) {
-	ctx := context.Background()
-
-	if !opts.Force {
+// FetchAllReleases fetches all releases from the GitHub repository, or from the mirror when one is set.
+// Unless forced, the releases cache is used until it expires (see cachePolicy).
+// In offline mode only the releases cache is read.
+// The requests are abandoned when ctx is done.
+func (gh *GithubHelper) FetchAllReleases(ctx context.Context, tool string, opts FetchOptions) (utils.ReleasesData, error) {
+	if utils.IsOffline() {
+		// the cache is all we have, however old it is
 		cacheData, err := utils.ReadFromCache(tool, opts.Limit)
-		if err == nil && cacheData.Releases != nil && len(cacheData.Releases) > 0 {
-			return cacheData, nil
+		if err != nil {
+			return utils.ReleasesData{}, err
+		}
+		if len(cacheData.Releases) == 0 {
+			return utils.ReleasesData{}, fmt.Errorf("%w: no cached releases of %s, run `vrsr %s list-remote` while online", utils.ErrOffline, tool, tool)
+		}
+		return cacheData, nil
+	}
+	if !opts.Force {
+		cacheData, err := utils.ReadFromCache(tool, 0)
+		if err == nil && cacheData.Satisfies(opts.Limit) {
+			ttl, staleWhileRevalidate, err := cachePolicy(tool)
+			if err != nil {
+				return utils.ReleasesData{}, err
+			}
+			if ttl == 0 || time.Since(cacheData.Timestamp) <= ttl {
+				return cacheData.First(opts.Limit), nil
+			}
+			if staleWhileRevalidate {
+				// serve the expired cache now, the next command gets the refreshed one
+				refreshInBackground(tool)
+				return cacheData.First(opts.Limit), nil
+			}
+			// the expired cache is refreshed below
+		}
+	}
+
+	// concurrent vrsr processes fetch the releases once, the ones waiting for the lock use the result
+	before, _ := utils.ReadFromCache(tool, 0)
+	unlock, err := utils.LockCache(ctx, os.Stderr, tool)
+	if err != nil {
+		return utils.ReleasesData{}, err
+	}
+	defer unlock()
+	if cached, err := utils.ReadFromCache(tool, 0); err == nil && cached.Satisfies(opts.Limit) && !cached.Timestamp.Equal(before.Timestamp) {
+		return cached.First(opts.Limit), nil
+	}
+	if base := mirror.URL(); base != "" {
+		data, err := mirror.FetchReleases(ctx, base, tool)
+		if err == nil {
+			if err := utils.WriteCache(tool, data); err != nil {
+				return utils.ReleasesData{}, err
+			}
+			if data.Satisfies(opts.Limit) {
+				return data.First(opts.Limit), nil
+			}
+			// the releases missing on the mirror are fetched upstream
+		} else if !errors.Is(err, mirror.ErrNotMirrored) {
+			// tools the mirror knows nothing about are fetched upstream
+			return utils.ReleasesData{}, err
 		}
 	}
 
-	totPages := 1
-	bar := progressbar.NewOptions(totPages,
+	bar := progressbar.NewOptions(1,
 		progressbar.OptionSetWidth(30),
 		progressbar.OptionSetDescription("Downloading releases metadata..."),
 		progressbar.OptionClearOnFinish(),
+		// keep stdout clean for machine-readable output
+		progressbar.OptionSetWriter(os.Stderr),
 	)
 	defer func() {
 		_ = bar.Finish()
 	}()
 
-	var allReleases []*github.RepositoryRelease
-	page := 1
+	if cached, err := utils.ReadFromCache(tool, 0); err == nil && len(cached.Releases) > 0 {
+		data, notes, err := gh.refreshReleases(ctx, opts.RepoConf, cached, bar)
+		if err != nil {
+			return utils.ReleasesData{}, err
+		}
+		if !data.Satisfies(opts.Limit) {
+			// the cache only holds the newest releases: the older ones are listed from the page
+			// before the first missing one, in case releases were deleted since
+			f := &releasesFetch{Releases: data.Releases, Notes: notes, Page: max(1, len(data.Releases)/100), PerPage: 100}
+			err = gh.fetchPages(ctx, opts.RepoConf, opts.Limit, f, bar)
+			data.Releases, data.Complete, notes = f.Releases, f.Complete, f.Notes
+			if err != nil {
+				// what was fetched so far is kept, the next fetch goes on from there
+				if err1 := utils.WriteCache(tool, data); err1 == nil {
+					saveReleaseNotes(tool, notes)
+				}
+				return utils.ReleasesData{}, err
+			}
+		}
+		if err := utils.WriteCache(tool, data); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to save release data to cache:", err)
+		}
+		saveReleaseNotes(tool, notes)
+		return data.First(opts.Limit), nil
+	}
 
 	// we use max possible value in order to limit occurrence of rate-limiting
-	limit := 100
-	if opts.Limit < 100 {
-		limit = opts.Limit
+	perPage := 100
+	if opts.Limit > 0 && opts.Limit < 100 {
+		perPage = opts.Limit
+	}
+	f := &releasesFetch{Notes: make(map[string]string), Page: 1, PerPage: perPage}
+	started := time.Now().UTC()
+	repoName := opts.RepoConf.Org + "/" + opts.RepoConf.Repo
+	if opts.Limit == 0 {
+		// pick up where a fetch interrupted by the rate limit left off
+		p, ok, err := utils.ReadPartialCache(tool)
+		if err == nil && ok && p.Repo == repoName && p.PerPage == perPage && time.Since(p.Timestamp) < partialMaxAge {
+			f.Releases, f.Page, started = p.Releases, p.NextPage, p.Timestamp
+			bar.Describe(fmt.Sprintf("Resuming download of releases metadata from page %d...", f.Page))
+		}
+	}
+	if err := gh.fetchPages(ctx, opts.RepoConf, opts.Limit, f, bar); err != nil {
+		if errors.Is(err, ErrRateLimited) && opts.Limit == 0 && len(f.Releases) > 0 {
+			saveReleaseNotes(tool, f.Notes)
+			if err1 := utils.SavePartialCache(tool, utils.PartialReleases{
+				Timestamp: started,
+				Repo:      repoName,
+				NextPage:  f.Page,
+				PerPage:   f.PerPage,
+				Releases:  f.Releases,
+			}); err1 == nil {
+				return utils.ReleasesData{}, fmt.Errorf("%w\nThe %d releases fetched so far were kept, run the command again to resume", err, len(f.Releases))
+			}
+		}
+		return utils.ReleasesData{}, err
 	}
 
-pages:
-	for {
-		releases, resp, err := gh.Repos.ListReleases(ctx, opts.RepoConf.Org, opts.RepoConf.Repo, &github.ListOptions{
-			Page:    page,
-			PerPage: limit,
+	// releases published since the fetch started may be missing, so it dates the cache
+	data := utils.ReleasesData{
+		Timestamp:    started,
+		Releases:     f.Releases,
+		Complete:     f.Complete,
+		ETag:         f.ETag,
+		LastModified: f.LastModified,
+	}
+	if err := utils.WriteCache(tool, data); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to save release data to cache:", err)
+	}
+	saveReleaseNotes(tool, f.Notes)
+	if err := utils.RemovePartialCache(tool); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to remove partial release data:", err)
+	}
+	return data, nil
+}
+
+// releasesFetch is the progress of a listing of the releases of a repository.
+type releasesFetch struct {
+	// Releases are the releases known so far, newest first, and Notes their release notes by tag.
+	Releases []utils.Release
+	Notes    map[string]string
+	// Page is the next page to list, PerPage releases at a time.
+	Page    int
+	PerPage int
+	// ETag and LastModified validate the first page, when it was listed 100 releases at a time.
+	ETag         string
+	LastModified string
+	// Complete is set once the last page was listed.
+	Complete bool
+}
+
+// fetchPages lists the releases of the repository from f.Page on, adding the ones not known yet to f,
+// until limit releases are known (all of them when limit is 0). On error, f holds the progress made.
+func (gh *GithubHelper) fetchPages(ctx context.Context, repo RepoConfDef, limit int, f *releasesFetch, bar *progressbar.ProgressBar) error {
+	seen := make(map[string]bool, len(f.Releases))
+	for _, r := range f.Releases {
+		seen[r.Tag] = true
+	}
+	for limit == 0 || len(f.Releases) < limit {
+		var releases []*github.RepositoryRelease
+		var resp *github.Response
+		err := gh.retryRateLimited(ctx, func() error {
+			var err error
+			releases, resp, err = gh.Repos.ListReleases(ctx, repo.Org, repo.Repo, &github.ListOptions{
+				Page:    f.Page,
+				PerPage: f.PerPage,
+			})
+			return err
 		})
 		if err != nil {
-			return utils.ReleasesData{}, err
+			return err
+		}
+		if f.Page == 1 && f.PerPage == 100 && resp.Response != nil {
+			// the validators of the first page allow conditional refreshes
+			f.ETag, f.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
 		}
-		if resp.LastPage > 1 && totPages != resp.LastPage {
+		if resp.LastPage > 1 {
 			bar.ChangeMax(resp.LastPage)
 		}
 
 		for _, r := range releases {
-			if opts.Limit > 0 && len(allReleases) >= opts.Limit {
-				break pages
+			if limit > 0 && len(f.Releases) >= limit {
+				return nil
+			}
+			// releases published while resuming shift the pages
+			if seen[r.GetTagName()] {
+				continue
+			}
+			seen[r.GetTagName()] = true
+			f.Releases = append(f.Releases, utils.NewRelease(r))
+			if r.GetBody() != "" {
+				f.Notes[r.GetTagName()] = r.GetBody()
 			}
-			allReleases = append(allReleases, r)
 		}
 
+		if resp.NextPage == 0 {
+			f.Complete = true
+			return nil
+		}
+		f.Page = resp.NextPage
+		_ = bar.Add(1)
+	}
+	return nil
+}
+
+// refreshReleases fetches the releases published since the cached ones and merges them with the cache,
+// returning the release notes of the new ones by tag.
+// The first page is requested conditionally, so an unchanged list costs no rate limit.
+func (gh *GithubHelper) refreshReleases(ctx context.Context, repo RepoConfDef, cached utils.ReleasesData, bar *progressbar.ProgressBar) (utils.ReleasesData, map[string]string, error) {
+	known := make(map[string]bool, len(cached.Releases))
+	for _, r := range cached.Releases {
+		known[r.Tag] = true
+	}
+	data := utils.ReleasesData{Timestamp: time.Now().UTC(), Complete: cached.Complete, ETag: cached.ETag, LastModified: cached.LastModified}
+	var fresh []*github.RepositoryRelease
+	page := 1
+	for {
+		reqCtx := ctx
+		if page == 1 {
+			reqCtx = withValidators(ctx, cached.ETag, cached.LastModified)
+		}
+		var releases []*github.RepositoryRelease
+		var resp *github.Response
+		err := gh.retryRateLimited(ctx, func() error {
+			var err error
+			releases, resp, err = gh.Repos.ListReleases(reqCtx, repo.Org, repo.Repo, &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			return err
+		})
+		if page == 1 && isNotModified(err) {
+			data.Releases = cached.Releases
+			return data, nil, nil
+		}
+		if err != nil {
+			return utils.ReleasesData{}, nil, err
+		}
+		if page == 1 && resp.Response != nil {
+			data.ETag, data.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		}
+		for _, r := range releases {
+			// releases are listed newest first, the rest is cached already
+			if known[r.GetTagName()] {
+				releases, notes := utils.CompactReleases(fresh)
+				data.Releases = append(releases, cached.Releases...)
+				return data, notes, nil
+			}
+			fresh = append(fresh, r)
+		}
 		if resp.NextPage == 0 {
 			break
 		}
 		page = resp.NextPage
 		_ = bar.Add(1)
 	}
+	// none of the cached releases is listed anymore
+	releases, notes := utils.CompactReleases(fresh)
+	data.Releases, data.Complete = releases, true
+	return data, notes, nil
+}
 
-	utils.SaveToCache(tool, allReleases)
+// ReleaseNotes returns the release notes of the tool version, fetching them from GitHub
+// when they were not stored along with the releases cache.
+func (gh *GithubHelper) ReleaseNotes(ctx context.Context, tool, tag string, repo RepoConfDef) (string, error) {
+	if body, ok, err := utils.ReadReleaseNotes(tool, tag); err == nil && ok {
+		return body, nil
+	}
+	if utils.IsOffline() {
+		return "", fmt.Errorf("%w: no stored release notes of %s %s", utils.ErrOffline, tool, tag)
+	}
+	rel, err := gh.getReleaseByTag(ctx, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	if unlock, err := utils.LockCache(ctx, os.Stderr, tool); err == nil {
+		saveReleaseNotes(tool, map[string]string{tag: rel.GetBody()})
+		unlock()
+	}
+	return rel.GetBody(), nil
+}
+
+// saveReleaseNotes stores the release notes of the tool by tag, reporting failures as they are not essential.
+func saveReleaseNotes(tool string, notes map[string]string) {
+	if err := utils.SaveReleaseNotes(tool, notes); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to save release notes:", err)
+	}
+}
 
-	return utils.ReleasesData{
-		Timestamp: time.Now().UTC(),
-		Releases:  allReleases,
-	}, nil
+// DownloadOptions tunes the verification done by DownloadRelease.
+type DownloadOptions struct {
+	// Verify checks the asset against its published SHA-256 checksum.
+	Verify bool
+	// Checksum is the expected SHA-256 of the asset (e.g. from the lockfile).
+	// When set, it is used instead of the published one.
+	Checksum string
+	// Progress is where the progress and the retries are reported, os.Stderr when nil.
+	Progress io.Writer
 }
 
-// DownloadRelease downloads the specified release version to the given vrsPath
-func (gh *GithubHelper) DownloadRelease(tool, version, vrsPath string, repo RepoConfDef) error {
-	ctx := context.Background()
+// DownloadRelease downloads the specified release version to the given vrsPath.
+// Assets served from a storage URL are retried and resumed, see utils.DownloadResumable.
+// The download is abandoned when ctx is done.
+func (gh *GithubHelper) DownloadRelease(ctx context.Context, tool, version, vrsPath string, repo RepoConfDef, opts DownloadOptions) error {
+	if err := utils.CheckOnline(fmt.Sprintf("download %s %s", tool, version)); err != nil {
+		return err
+	}
+	progress := opts.Progress
+	if progress == nil {
+		// keep stdout clean for machine-readable output
+		progress = os.Stderr
+	}
 	bar := progressbar.NewOptions(-1,
 		progressbar.OptionSetWidth(30),
 		progressbar.OptionSetDescription("Downloading release metadata..."),
 		progressbar.OptionClearOnFinish(),
+		progressbar.OptionSetWriter(progress),
 	)
 	defer func() {
 		_ = bar.Finish()
 	}()
-	rel, _, err := gh.Repos.GetReleaseByTag(ctx, repo.Org, repo.Repo, version)
+	rel, err := gh.getReleaseByTag(ctx, repo, version)
 	if err != nil {
 		return err
 	}
@@ -144,79 +426,353 @@ func (gh *GithubHelper) DownloadRelease(tool, version, vrsPath string, repo Repo
 	}
 
 	bar.Describe("Finding the right asset to download...")
-	osAlias := strings.ToLower(runtime.GOOS)
-	archAlias := strings.ToLower(runtime.GOARCH)
+	data := NewTemplateData(tool, version)
+	asset, err := findAsset(rel.Assets, data, repo.AssetPattern)
+	if err != nil {
+		return err
+	}
+	archive, err := repo.ArchiveSpec(data)
+	if err != nil {
+		return err
+	}
+	checksum := opts.Checksum
+	if checksum == "" && opts.Verify {
+		bar.Describe("Retrieving checksum...")
+		checksum, err = gh.findChecksum(ctx, rel.Assets, asset, data, repo)
+		if err != nil {
+			return err
+		}
+	}
 
-	relName := tool + "-" + osAlias + "-" + archAlias
+	artifact := utils.Artifact{
+		Tool:      tool,
+		Version:   version,
+		SourceURL: asset.GetBrowserDownloadURL(),
+		Archive:   archive,
+		Checksum:  checksum,
+	}
+	bar.Describe("Downloading...")
+	var rc io.ReadCloser
+	var loc string
+	err = utils.Retry(ctx, progress, "Locating the asset", func() error {
+		var err error
+		rc, loc, err = gh.locateReleaseAsset(ctx, repo, asset.GetID())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download asset: %w", err)
+	}
+	if rc == nil {
+		// the asset is served from a storage URL, which can be resumed; its signature
+		// expires, so the partial download is keyed by the asset instead
+		_ = bar.Finish()
+		key := fmt.Sprintf("%s/releases/assets/%d", repo.Source(), asset.GetID())
+		return utils.DownloadResumable(ctx, progress, key, loc, func(r io.Reader) error {
+			return utils.SaveBinary(r, vrsPath, artifact)
+		})
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	return utils.SaveBinary(rc, vrsPath, artifact)
+}
 
-	var asset *github.ReleaseAsset
-	for _, a := range rel.Assets {
-		if a == nil {
-			continue
+// PlatformArtifact is the artifact of a tool version for a given platform.
+type PlatformArtifact struct {
+	URL    string
+	SHA256 string
+	// Verified is set by FetchArtifact when SHA256 was checked against a published or expected
+	// checksum, rather than only computed from the download.
+	Verified bool
+}
+
+// ReleaseArtifacts returns the artifact of the tool version for each of the given
+// platforms ("os/arch"). The published checksums are used when available, otherwise
+// the artifact is downloaded and hashed.
+func (gh *GithubHelper) ReleaseArtifacts(ctx context.Context, tool, version string, repo RepoConfDef, platforms []string) (map[string]PlatformArtifact, error) {
+	if err := utils.CheckOnline(fmt.Sprintf("look up the %s %s artifacts", tool, version)); err != nil {
+		return nil, err
+	}
+	var rel *github.RepositoryRelease
+	if repo.DownloadURL == "" {
+		var err error
+		rel, err = gh.getReleaseByTag(ctx, repo, version)
+		if err != nil {
+			return nil, err
 		}
-		lname := strings.ToLower(a.GetName())
-		if !strings.HasPrefix(lname, relName) {
-			// not the right asset
-			continue
+		if rel == nil {
+			return nil, errReleaseNotFound
 		}
-		if osAlias == "windows" && !strings.HasSuffix(lname, ".exe") {
-			// windows binary must have .exe suffix
-			continue
+	}
+
+	artifacts := make(map[string]PlatformArtifact, len(platforms))
+	for _, platform := range platforms {
+		data, err := NewPlatformTemplateData(tool, version, platform)
+		if err != nil {
+			return nil, err
 		}
-		if osAlias != "windows" && len(strings.Split(lname, ".")) > 1 {
-			// non-windows binaries should not have an extension
-			continue
+		var a PlatformArtifact
+		if rel == nil {
+			a, err = downloadArtifact(ctx, repo, data)
+		} else {
+			a, err = gh.releaseArtifact(ctx, rel, repo, data)
 		}
-		asset = a
-	}
-	if asset == nil {
-		return errReleaseNotFound
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		artifacts[platform] = a
 	}
+	return artifacts, nil
+}
 
-	// download asset using go-github helper (returns ReadCloser)
-	bar.Describe("Downloading...")
-	rc, _, err := gh.Repos.DownloadReleaseAsset(ctx, repo.Org, repo.Repo, asset.GetID(), http.DefaultClient)
+// FetchArtifactOptions tunes the verification done by FetchArtifact.
+type FetchArtifactOptions struct {
+	// Checksum is the expected SHA-256 of the artifact (e.g. from the lockfile).
+	Checksum string
+	// SkipVerify accepts an artifact with neither a published nor an expected checksum,
+	// whose hash is then only computed (unsafe).
+	SkipVerify bool
+}
+
+// FetchArtifact writes the raw artifact of the tool version for the platform ("os/arch") to w.
+// It is verified against its published SHA-256 and the expected one, if any. Without either
+// it is not downloaded and utils.ErrChecksumNotFound is returned, unless verification is skipped.
+func (gh *GithubHelper) FetchArtifact(ctx context.Context, tool, version string, repo RepoConfDef, platform string, opts FetchArtifactOptions, w io.Writer) (PlatformArtifact, error) {
+	if err := utils.CheckOnline(fmt.Sprintf("download %s %s", tool, version)); err != nil {
+		return PlatformArtifact{}, err
+	}
+	data, err := NewPlatformTemplateData(tool, version, platform)
 	if err != nil {
-		return fmt.Errorf("failed to download asset: %w", err)
+		return PlatformArtifact{}, err
+	}
+
+	var a PlatformArtifact
+	var rc io.ReadCloser
+	if repo.DownloadURL != "" {
+		if a.URL, err = Render(repo.DownloadURL, data); err != nil {
+			return a, err
+		}
+		a.SHA256, err = repo.DownloadChecksum(ctx, data, a.URL)
+		if err := checkArtifactChecksum(err, a.SHA256, opts); err != nil {
+			return a, err
+		}
+		resp, err := utils.Get(ctx, a.URL)
+		if err != nil {
+			return a, fmt.Errorf("failed to send request: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return a, fmt.Errorf("bad status: %s", resp.Status)
+		}
+		rc = resp.Body
+	} else {
+		rel, err := gh.getReleaseByTag(ctx, repo, version)
+		if err != nil {
+			return a, err
+		}
+		if rel == nil {
+			return a, errReleaseNotFound
+		}
+		asset, err := findAsset(rel.Assets, data, repo.AssetPattern)
+		if err != nil {
+			return a, err
+		}
+		a.URL = asset.GetBrowserDownloadURL()
+		a.SHA256, err = gh.findChecksum(ctx, rel.Assets, asset, data, repo)
+		if err := checkArtifactChecksum(err, a.SHA256, opts); err != nil {
+			return a, err
+		}
+		rc, err = gh.downloadReleaseAsset(ctx, repo, asset.GetID())
+		if err != nil {
+			return a, fmt.Errorf("failed to download asset: %w", err)
+		}
 	}
 	defer func() {
 		_ = rc.Close()
 	}()
 
-	// write to temp file then move (safer)
-	finalPath := filepath.Join(vrsPath, tool)
-	err = utils.EnsurePathExists(finalPath)
+	sum, err := utils.HashReader(io.TeeReader(rc, w))
 	if err != nil {
-		return fmt.Errorf("error ensuring vrs path exists: %w", err)
+		return a, err
+	}
+	for _, expected := range []string{a.SHA256, opts.Checksum} {
+		if expected != "" && expected != sum {
+			return a, fmt.Errorf("%w: expected %s, got %s", utils.ErrChecksumMismatch, expected, sum)
+		}
+	}
+	a.Verified = a.SHA256 != "" || opts.Checksum != ""
+	a.SHA256 = sum
+	return a, nil
+}
+
+// checkArtifactChecksum returns the error looking up the published checksum of an artifact, if any,
+// utils.ErrChecksumNotFound when there is nothing to verify the artifact against and verification
+// is not skipped.
+func checkArtifactChecksum(err error, published string, opts FetchArtifactOptions) error {
+	if err != nil && !errors.Is(err, utils.ErrChecksumNotFound) {
+		return err
 	}
-	tmpFile, err := os.CreateTemp(finalPath, tool+"-download-*")
+	if published == "" && opts.Checksum == "" && !opts.SkipVerify {
+		return fmt.Errorf("%w: no published or locked checksum to verify the artifact against", utils.ErrChecksumNotFound)
+	}
+	return nil
+}
+
+// downloadArtifact returns the artifact of the given platform for tools downloaded from DownloadURL.
+func downloadArtifact(ctx context.Context, repo RepoConfDef, data TemplateData) (PlatformArtifact, error) {
+	dlURL, err := Render(repo.DownloadURL, data)
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return PlatformArtifact{}, err
 	}
-	// Ensure cleanup if something goes wrong
-	defer func() {
+	sum, err := repo.DownloadChecksum(ctx, data, dlURL)
+	if errors.Is(err, utils.ErrChecksumNotFound) {
+		sum, err = utils.HashURL(ctx, dlURL)
+	}
+	if err != nil {
+		return PlatformArtifact{}, err
+	}
+	return PlatformArtifact{URL: dlURL, SHA256: sum}, nil
+}
+
+// releaseArtifact returns the artifact of the given platform among the release assets.
+func (gh *GithubHelper) releaseArtifact(ctx context.Context, rel *github.RepositoryRelease, repo RepoConfDef, data TemplateData) (PlatformArtifact, error) {
+	asset, err := findAsset(rel.Assets, data, repo.AssetPattern)
+	if err != nil {
+		return PlatformArtifact{}, err
+	}
+	sum, err := gh.findChecksum(ctx, rel.Assets, asset, data, repo)
+	if errors.Is(err, utils.ErrChecksumNotFound) {
+		var rc io.ReadCloser
+		rc, err = gh.downloadReleaseAsset(ctx, repo, asset.GetID())
 		if err != nil {
-			_ = os.Remove(tmpFile.Name())
+			return PlatformArtifact{}, fmt.Errorf("failed to download asset: %w", err)
 		}
-	}()
-	_, err = io.Copy(tmpFile, rc)
-	if err1 := tmpFile.Close(); err == nil && err1 != nil {
-		err = err1
+		sum, err = utils.HashReader(rc)
+		_ = rc.Close()
 	}
 	if err != nil {
-		_ = os.Remove(tmpFile.Name())
-		return fmt.Errorf("failed to save download: %w", err)
+		return PlatformArtifact{}, err
 	}
+	return PlatformArtifact{URL: asset.GetBrowserDownloadURL(), SHA256: sum}, nil
+}
 
-	// move to destination
-	destPath := filepath.Join(finalPath, tool+"-"+version)
-	if err := os.Rename(tmpFile.Name(), destPath); err != nil {
-		return fmt.Errorf("failed to move downloaded file to destination: %w", err)
+// checksumAssets are the aggregated checksum files commonly published alongside release assets.
+var checksumAssets = []string{"sha256sum.txt", "sha256sums.txt", "SHA256SUMS", "checksums.txt"}
+
+// findChecksum returns the published SHA-256 of the given release asset.
+// The configured checksum asset wins, then the digest reported by GitHub,
+// then "<asset>.sha256sum", "<asset>.sha256" and the usual aggregated checksum files.
+func (gh *GithubHelper) findChecksum(ctx context.Context, assets []*github.ReleaseAsset, asset *github.ReleaseAsset, data TemplateData, repo RepoConfDef) (string, error) {
+	candidates := []string{}
+	if repo.ChecksumAsset != "" {
+		name, err := Render(repo.ChecksumAsset, data)
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, name)
+	} else {
+		if sum := utils.NormalizeDigest(asset.GetDigest()); sum != "" {
+			return sum, nil
+		}
+		candidates = append(candidates, asset.GetName()+".sha256sum", asset.GetName()+".sha256")
+		candidates = append(candidates, checksumAssets...)
 	}
 
-	// make it executable
-	if err := os.Chmod(destPath, 0755); err != nil {
-		return fmt.Errorf("failed to set executable permission: %w", err)
+	for _, name := range candidates {
+		for _, a := range assets {
+			if a == nil || !strings.EqualFold(a.GetName(), name) {
+				continue
+			}
+			rc, err := gh.downloadReleaseAsset(ctx, repo, a.GetID())
+			if err != nil {
+				return "", fmt.Errorf("failed to download checksum: %w", err)
+			}
+			content, err := io.ReadAll(io.LimitReader(rc, 1<<20))
+			_ = rc.Close()
+			if err != nil {
+				return "", fmt.Errorf("failed to read checksum: %w", err)
+			}
+			return utils.ParseChecksum(content, asset.GetName())
+		}
 	}
-	return nil
+	return "", fmt.Errorf("%w for %s", utils.ErrChecksumNotFound, asset.GetName())
+}
+
+// getReleaseByTag returns the release of the tag, waiting for the rate limit when it is near its reset.
+func (gh *GithubHelper) getReleaseByTag(ctx context.Context, repo RepoConfDef, tag string) (*github.RepositoryRelease, error) {
+	var rel *github.RepositoryRelease
+	err := gh.retryRateLimited(ctx, func() error {
+		var err error
+		rel, _, err = gh.Repos.GetReleaseByTag(ctx, repo.Org, repo.Repo, tag)
+		return err
+	})
+	return rel, err
+}
+
+// downloadReleaseAsset returns the content of the release asset, waiting for the rate limit when it is near its reset.
+func (gh *GithubHelper) downloadReleaseAsset(ctx context.Context, repo RepoConfDef, id int64) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := gh.retryRateLimited(ctx, func() error {
+		var err error
+		rc, _, err = gh.Repos.DownloadReleaseAsset(ctx, repo.Org, repo.Repo, id, utils.HTTPClient())
+		return err
+	})
+	return rc, err
+}
+
+// locateReleaseAsset returns the URL the release asset is downloaded from or, when the API serves
+// it directly, its content. It waits for the rate limit when it is near its reset.
+func (gh *GithubHelper) locateReleaseAsset(ctx context.Context, repo RepoConfDef, id int64) (io.ReadCloser, string, error) {
+	var rc io.ReadCloser
+	var loc string
+	err := gh.retryRateLimited(ctx, func() error {
+		var err error
+		// without a client to follow it, the redirect is returned
+		rc, loc, err = gh.Repos.DownloadReleaseAsset(ctx, repo.Org, repo.Repo, id, nil)
+		return err
+	})
+	return rc, loc, err
+}
+
+// findAsset returns the release asset matching the asset pattern for the current OS/ARCH.
+// Without a pattern, the asset named "<tool>-<os>-<arch>" (plus ".exe" on windows) is used.
+func findAsset(assets []*github.ReleaseAsset, data TemplateData, pattern string) (*github.ReleaseAsset, error) {
+	if pattern != "" {
+		name, err := Render(pattern, data)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assets {
+			if a != nil && strings.EqualFold(a.GetName(), name) {
+				return a, nil
+			}
+		}
+		return nil, errReleaseNotFound
+	}
+
+	relName := data.Tool + "-" + data.OS + "-" + data.Arch
+	var asset *github.ReleaseAsset
+	for _, a := range assets {
+		if a == nil {
+			continue
+		}
+		lname := strings.ToLower(a.GetName())
+		if !strings.HasPrefix(lname, relName) {
+			// not the right asset
+			continue
+		}
+		if data.OS == "windows" && !strings.HasSuffix(lname, ".exe") {
+			// windows binary must have .exe suffix
+			continue
+		}
+		if data.OS != "windows" && len(strings.Split(lname, ".")) > 1 {
+			// non-windows binaries should not have an extension
+			continue
+		}
+		asset = a
+	}
+	if asset == nil {
+		return nil, errReleaseNotFound
+	}
+	return asset, nil
 }
@@ -1,19 +1,38 @@
 package github
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
 	"github.com/stepbeta/vrsr/internal/utils"
 )
 
+// cacheReleases stores the releases of the tool in its cache, as a complete fetch does.
+func cacheReleases(t *testing.T, tool string, rels []*gh.RepositoryRelease) {
+	t.Helper()
+	releases, notes := utils.CompactReleases(rels)
+	if err := utils.WriteCache(tool, utils.ReleasesData{Timestamp: time.Now().UTC(), Releases: releases, Complete: true}); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	if err := utils.SaveReleaseNotes(tool, notes); err != nil {
+		t.Fatalf("failed to save release notes: %v", err)
+	}
+}
+
 func TestNew_NoTokenAndWithToken(t *testing.T) {
 	// preserve env
 	orig := os.Getenv("GITHUB_TOKEN")
@@ -61,11 +80,11 @@ func TestFetchAllReleases_UsesCacheAndLimit(t *testing.T) {
 	}
 
 	// save to cache (uses GetCachePath which will look under HOME)
-	utils.SaveToCache(tool, rels)
+	cacheReleases(t, tool, rels)
 
 	ghh := New(nil)
 	// non-forced fetch should return cache
-	data, err := ghh.FetchAllReleases(tool, FetchOptions{Force: false, Limit: 0, RepoConf: RepoConfDef{}})
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: false, Limit: 0, RepoConf: RepoConfDef{}})
 	if err != nil {
 		t.Fatalf("FetchAllReleases returned error: %v", err)
 	}
@@ -74,7 +93,7 @@ func TestFetchAllReleases_UsesCacheAndLimit(t *testing.T) {
 	}
 
 	// test limit
-	data2, err := ghh.FetchAllReleases(tool, FetchOptions{Force: false, Limit: 2, RepoConf: RepoConfDef{}})
+	data2, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: false, Limit: 2, RepoConf: RepoConfDef{}})
 	if err != nil {
 		t.Fatalf("FetchAllReleases with limit returned error: %v", err)
 	}
@@ -97,6 +116,9 @@ func TestFetchAllReleases_UsesCacheAndLimit(t *testing.T) {
 
 type fakeReposForTest struct {
 	releases []*gh.RepositoryRelease
+	content  []byte
+	// redirect is the storage URL the assets are served from, when set
+	redirect string
 }
 
 func (f *fakeReposForTest) ListReleases(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.RepositoryRelease, *gh.Response, error) {
@@ -113,6 +135,12 @@ func (f *fakeReposForTest) GetReleaseByTag(ctx context.Context, owner, repo, tag
 }
 
 func (f *fakeReposForTest) DownloadReleaseAsset(ctx context.Context, owner, repo string, assetID int64, httpClient *http.Client) (io.ReadCloser, string, error) {
+	if f.redirect != "" && httpClient == nil {
+		return nil, f.redirect, nil
+	}
+	if f.content != nil {
+		return io.NopCloser(bytes.NewReader(f.content)), "", nil
+	}
 	return io.NopCloser(strings.NewReader("ok")), "", nil
 }
 
@@ -124,7 +152,7 @@ func TestFetchAllReleases_ForcedUsesInjectedRepos(t *testing.T) {
 	fake := &fakeReposForTest{releases: rels}
 	ghh := GithubHelper{Client: nil, Repos: fake}
 
-	data, err := ghh.FetchAllReleases("ftool", FetchOptions{Force: true, Limit: 0, RepoConf: RepoConfDef{}})
+	data, err := ghh.FetchAllReleases(context.Background(), "ftool", FetchOptions{Force: true, Limit: 0, RepoConf: RepoConfDef{}})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -152,7 +180,7 @@ func TestDownloadRelease_Success(t *testing.T) {
 	ghh := GithubHelper{Client: nil, Repos: fake}
 
 	// call DownloadRelease
-	if err := ghh.DownloadRelease(tool, version, vrsPath, RepoConfDef{Org: "o", Repo: "r"}); err != nil {
+	if err := ghh.DownloadRelease(context.Background(), tool, version, vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{}); err != nil {
 		t.Fatalf("DownloadRelease returned error: %v", err)
 	}
 
@@ -176,6 +204,40 @@ func TestDownloadRelease_Success(t *testing.T) {
 	}
 }
 
+func TestDownloadRelease_RetriesStorageDownload(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "versions")
+	tool := "retrytool"
+	version := "v1.2.3"
+	var requests int
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("stored"))
+	}))
+	defer storage.Close()
+	rel := &gh.RepositoryRelease{
+		TagName: gh.Ptr(version),
+		Assets:  []*gh.ReleaseAsset{{Name: gh.Ptr(tool + "-" + runtime.GOOS + "-" + runtime.GOARCH), ID: gh.Ptr(int64(7))}},
+	}
+	ghh := GithubHelper{Repos: &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}, redirect: storage.URL + "/signed"}}
+
+	if err := ghh.DownloadRelease(context.Background(), tool, version, vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadRelease returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the failed download to be retried once, got %d requests", requests)
+	}
+	b, err := os.ReadFile(filepath.Join(vrsPath, tool, tool+"-"+version))
+	if err != nil || string(b) != "stored" {
+		t.Fatalf("expected the asset to be installed from the storage URL, got %q (%v)", b, err)
+	}
+}
+
 func TestDownloadRelease_AssetNotFound(t *testing.T) {
 	td := t.TempDir()
 	vrsPath := filepath.Join(td, "versions")
@@ -190,7 +252,7 @@ func TestDownloadRelease_AssetNotFound(t *testing.T) {
 	fake := &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}}
 	ghh := GithubHelper{Client: nil, Repos: fake}
 
-	err := ghh.DownloadRelease(tool, version, vrsPath, RepoConfDef{Org: "o", Repo: "r"})
+	err := ghh.DownloadRelease(context.Background(), tool, version, vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{})
 	if err == nil {
 		t.Fatalf("expected error when asset not found")
 	}
@@ -198,3 +260,242 @@ func TestDownloadRelease_AssetNotFound(t *testing.T) {
 		t.Fatalf("expected errReleaseNotFound, got: %v", err)
 	}
 }
+
+func TestDownloadRelease_AssetPatternAndArchive(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	tool := "arctool"
+	version := "v0.4.2"
+
+	// build a tar.gz holding the binary in a nested folder
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	body := []byte("binary")
+	if err := tw.WriteHeader(&tar.Header{Name: "dist/" + tool, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("failed to write tar header: %v", err)
+	}
+	if _, err := tw.Write(body); err != nil {
+		t.Fatalf("failed to write tar body: %v", err)
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+
+	repo := RepoConfDef{
+		Org:          "o",
+		Repo:         "r",
+		AssetPattern: "{{.Tool}}_{{trimv .Version}}_{{.OS | title}}_{{.Arch}}.tar.gz",
+		ArchiveType:  utils.ArchiveTarGz,
+	}
+	assetName, err := Render(repo.AssetPattern, NewTemplateData(tool, version))
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	rel := &gh.RepositoryRelease{
+		TagName: gh.Ptr(version),
+		Assets: []*gh.ReleaseAsset{
+			{Name: gh.Ptr(assetName + ".sha256"), ID: gh.Ptr(int64(1))},
+			{Name: gh.Ptr(assetName), ID: gh.Ptr(int64(2))},
+		},
+	}
+	fake := &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}, content: buf.Bytes()}
+	ghh := GithubHelper{Client: nil, Repos: fake}
+
+	if err := ghh.DownloadRelease(context.Background(), tool, version, vrsPath, repo, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadRelease returned error: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(vrsPath, tool, tool+"-"+version))
+	if err != nil {
+		t.Fatalf("failed to read extracted file: %v", err)
+	}
+	if string(b) != "binary" {
+		t.Fatalf("unexpected file content: %s", string(b))
+	}
+}
+
+func TestDownloadRelease_VerifiesDigest(t *testing.T) {
+	td := t.TempDir()
+	vrsPath := filepath.Join(td, "versions")
+	tool := "sumtool"
+	assetName := tool + "-" + strings.ToLower(runtime.GOOS) + "-" + strings.ToLower(runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		assetName += ".exe"
+	}
+	// sha256("ok")
+	okSum := "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df"
+
+	newHelper := func(version, digest string) GithubHelper {
+		rel := &gh.RepositoryRelease{
+			TagName: gh.Ptr(version),
+			Assets:  []*gh.ReleaseAsset{{Name: gh.Ptr(assetName), ID: gh.Ptr(int64(7)), Digest: gh.Ptr(digest)}},
+		}
+		return GithubHelper{Repos: &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}}}
+	}
+
+	ghh := newHelper("v1.0.0", "sha256:"+okSum)
+	if err := ghh.DownloadRelease(context.Background(), tool, "v1.0.0", vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{Verify: true}); err != nil {
+		t.Fatalf("expected matching digest to verify, got: %v", err)
+	}
+	m, err := utils.ReadManifest(vrsPath, tool, "v1.0.0")
+	if err != nil {
+		t.Fatalf("expected a manifest to be written: %v", err)
+	}
+	if m.SHA256 != okSum || m.Version != "1.0.0" || m.Binary != tool+"-v1.0.0" || m.InstalledAt.IsZero() {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+
+	ghh = newHelper("v1.0.1", "sha256:"+strings.Repeat("0", 64))
+	err = ghh.DownloadRelease(context.Background(), tool, "v1.0.1", vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{Verify: true})
+	if !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vrsPath, tool, tool+"-v1.0.1")); !os.IsNotExist(err) {
+		t.Fatalf("expected binary not to be installed on checksum mismatch")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(vrsPath, tool, tool+"-download-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("expected temp files to be removed, found %v", leftovers)
+	}
+
+	ghh = newHelper("v1.0.2", "")
+	err = ghh.DownloadRelease(context.Background(), tool, "v1.0.2", vrsPath, RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{Verify: true})
+	if !errors.Is(err, utils.ErrChecksumNotFound) {
+		t.Fatalf("expected checksum not found, got: %v", err)
+	}
+}
+
+func TestReleaseArtifacts_PerPlatform(t *testing.T) {
+	tool := "locktool"
+	// sha256("ok")
+	okSum := "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df"
+	digestSum := strings.Repeat("a", 64)
+	rel := &gh.RepositoryRelease{
+		TagName: gh.Ptr("v1.0.0"),
+		Assets: []*gh.ReleaseAsset{
+			{Name: gh.Ptr(tool + "-linux-amd64"), ID: gh.Ptr(int64(1)), Digest: gh.Ptr("sha256:" + digestSum),
+				BrowserDownloadURL: gh.Ptr("https://example.com/" + tool + "-linux-amd64")},
+			// no published checksum, the asset is hashed
+			{Name: gh.Ptr(tool + "-darwin-arm64"), ID: gh.Ptr(int64(2)),
+				BrowserDownloadURL: gh.Ptr("https://example.com/" + tool + "-darwin-arm64")},
+		},
+	}
+	ghh := GithubHelper{Repos: &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}}}
+	repo := RepoConfDef{Org: "o", Repo: "r"}
+
+	artifacts, err := ghh.ReleaseArtifacts(context.Background(), tool, "v1.0.0", repo, []string{"linux/amd64", "darwin/arm64"})
+	if err != nil {
+		t.Fatalf("ReleaseArtifacts returned error: %v", err)
+	}
+	if a := artifacts["linux/amd64"]; a.SHA256 != digestSum || a.URL != "https://example.com/"+tool+"-linux-amd64" {
+		t.Fatalf("unexpected linux/amd64 artifact: %+v", a)
+	}
+	if a := artifacts["darwin/arm64"]; a.SHA256 != okSum {
+		t.Fatalf("expected darwin/arm64 asset to be hashed, got: %+v", a)
+	}
+
+	if _, err := ghh.ReleaseArtifacts(context.Background(), tool, "v1.0.0", repo, []string{"windows/amd64"}); !errors.Is(err, errReleaseNotFound) {
+		t.Fatalf("expected missing platform to fail, got: %v", err)
+	}
+	if _, err := ghh.ReleaseArtifacts(context.Background(), tool, "v1.0.0", repo, []string{"linux"}); err == nil {
+		t.Fatalf("expected invalid platform to fail")
+	}
+}
+
+func TestFetchArtifact_VerifiesPublishedChecksum(t *testing.T) {
+	tool := "bundletool"
+	rel := &gh.RepositoryRelease{
+		TagName: gh.Ptr("v1.0.0"),
+		Assets: []*gh.ReleaseAsset{
+			// the published digest does not match the downloaded content
+			{Name: gh.Ptr(tool + "-linux-amd64"), ID: gh.Ptr(int64(1)), Digest: gh.Ptr("sha256:" + strings.Repeat("a", 64))},
+			{Name: gh.Ptr(tool + "-darwin-arm64"), ID: gh.Ptr(int64(2)),
+				BrowserDownloadURL: gh.Ptr("https://example.com/" + tool + "-darwin-arm64")},
+		},
+	}
+	ghh := GithubHelper{Repos: &fakeReposForTest{releases: []*gh.RepositoryRelease{rel}}}
+	repo := RepoConfDef{Org: "o", Repo: "r"}
+
+	// sha256("ok")
+	okSum := "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df"
+	// no published checksum: refused unless one is expected or verification is skipped
+	if _, err := ghh.FetchArtifact(context.Background(), tool, "v1.0.0", repo, "darwin/arm64", FetchArtifactOptions{}, io.Discard); !errors.Is(err, utils.ErrChecksumNotFound) {
+		t.Fatalf("expected missing checksum, got: %v", err)
+	}
+	var sb strings.Builder
+	a, err := ghh.FetchArtifact(context.Background(), tool, "v1.0.0", repo, "darwin/arm64", FetchArtifactOptions{Checksum: okSum}, &sb)
+	if err != nil {
+		t.Fatalf("FetchArtifact returned error: %v", err)
+	}
+	if sb.String() != "ok" || a.SHA256 != okSum || !a.Verified || a.URL != "https://example.com/"+tool+"-darwin-arm64" {
+		t.Fatalf("unexpected artifact %+v with content %q", a, sb.String())
+	}
+	if a, err := ghh.FetchArtifact(context.Background(), tool, "v1.0.0", repo, "darwin/arm64", FetchArtifactOptions{SkipVerify: true}, io.Discard); err != nil || a.SHA256 != okSum || a.Verified {
+		t.Fatalf("expected an unverified artifact, got %+v, %v", a, err)
+	}
+	if _, err := ghh.FetchArtifact(context.Background(), tool, "v1.0.0", repo, "darwin/arm64", FetchArtifactOptions{Checksum: strings.Repeat("b", 64)}, io.Discard); !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got: %v", err)
+	}
+	if _, err := ghh.FetchArtifact(context.Background(), tool, "v1.0.0", repo, "linux/amd64", FetchArtifactOptions{}, io.Discard); !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got: %v", err)
+	}
+}
+
+func TestOfflineMode_NeverUsesNetwork(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set("offline", true)
+	defer viper.Set("offline", false)
+	tool := "offtool"
+	// a nil Repos makes any API call panic
+	ghh := GithubHelper{}
+
+	_, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true})
+	if !errors.Is(err, utils.ErrOffline) {
+		t.Fatalf("expected offline error without cache, got: %v", err)
+	}
+	cacheReleases(t, tool, []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.0.0")}})
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true})
+	if err != nil || len(data.Releases) != 1 {
+		t.Fatalf("expected forced fetch to read the cache when offline, got %d releases, %v", len(data.Releases), err)
+	}
+
+	err = ghh.DownloadRelease(context.Background(), tool, "v1.0.0", t.TempDir(), RepoConfDef{Org: "o", Repo: "r"}, DownloadOptions{Verify: true})
+	if !errors.Is(err, utils.ErrOffline) {
+		t.Fatalf("expected offline error on download, got: %v", err)
+	}
+	_, err = ghh.ReleaseArtifacts(context.Background(), tool, "v1.0.0", RepoConfDef{DownloadURL: "https://example.com/{{.Tool}}"}, []string{"linux/amd64"})
+	if !errors.Is(err, utils.ErrOffline) {
+		t.Fatalf("expected offline error on artifacts lookup, got: %v", err)
+	}
+}
+
+func TestFetchAllReleases_CompletesLimitedCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tool := "limitedtool"
+	fake := &pagedReposForTest{pages: releasePages()}
+	ghh := GithubHelper{Repos: fake}
+
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Limit: 1, RepoConf: RepoConfDef{Org: "o", Repo: "r"}})
+	if err != nil || len(data.Releases) != 1 {
+		t.Fatalf("expected 1 release, got %d (%v)", len(data.Releases), err)
+	}
+	cached, err := utils.ReadFromCache(tool, 0)
+	if err != nil || len(cached.Releases) != 1 || cached.Complete {
+		t.Fatalf("expected a partial cache of 1 release, got %+v (%v)", cached, err)
+	}
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Limit: 1, RepoConf: RepoConfDef{Org: "o", Repo: "r"}}); err != nil || len(data.Releases) != 1 || len(fake.calls) != 1 {
+		t.Fatalf("expected the partial cache to serve the same limit, got %d releases, calls %v (%v)", len(data.Releases), fake.calls, err)
+	}
+
+	// the partial cache cannot serve the whole list, the missing releases are fetched
+	data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{RepoConf: RepoConfDef{Org: "o", Repo: "r"}})
+	if err != nil || len(data.Releases) != 3 || !data.Complete {
+		t.Fatalf("expected the 3 releases, got %+v (%v)", data, err)
+	}
+	if data.Releases[0].Tag != "v3.0.0" || data.Releases[2].Tag != "v1.0.0" {
+		t.Fatalf("unexpected order of the merged releases: %+v", data.Releases)
+	}
+	calls := len(fake.calls)
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Limit: 2, RepoConf: RepoConfDef{Org: "o", Repo: "r"}}); err != nil || len(data.Releases) != 2 || len(fake.calls) != calls {
+		t.Fatalf("expected the complete cache to serve any limit, got %d releases, calls %v (%v)", len(data.Releases), fake.calls, err)
+	}
+}
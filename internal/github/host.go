@@ -0,0 +1,34 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// publicHost is the host of the public GitHub instance.
+const publicHost = "github.com"
+
+// Host returns the GitHub host served by the API at apiURL, e.g. "github.com" for
+// https://api.github.com/ and "github.example.com" for https://github.example.com/api/v3/.
+func Host(apiURL *url.URL) string {
+	if apiURL == nil {
+		return publicHost
+	}
+	return strings.TrimPrefix(strings.ToLower(apiURL.Hostname()), "api.")
+}
+
+// validateAPIURL checks that u, when set, is an absolute http(s) URL.
+func validateAPIURL(u string) error {
+	if u == "" {
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("invalid GitHub API URL %q: %w", u, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid GitHub API URL %q: expected an absolute http(s) URL", u)
+	}
+	return nil
+}
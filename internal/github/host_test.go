@@ -0,0 +1,85 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestHost(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://api.github.com/":            publicHost,
+		"https://github.example.com/api/v3/": "github.example.com",
+		"https://API.ghe.example.com:8443/":  "ghe.example.com",
+		"http://127.0.0.1:8080/api/v3/":      "127.0.0.1",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", raw, err)
+		}
+		if got := Host(u); got != want {
+			t.Fatalf("expected host of %s to be %s, got %s", raw, want, got)
+		}
+	}
+}
+
+func TestNewFor_EnterpriseURLsAndPerHostTokens(t *testing.T) {
+	isolateCredentials(t)
+	t.Setenv("GITHUB_TOKEN", "public-token")
+	auth := map[string]string{}
+	newGHE := func(name string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/repos/o/r/releases" {
+				http.NotFound(w, r)
+				return
+			}
+			auth[name] = r.Header.Get("Authorization")
+			_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0"}]`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	global, perTool := newGHE("global"), newGHE("per-tool")
+
+	viper.Set("github.base-url", global.URL+"/api/v3/")
+	viper.Set("github.tokens", map[string]string{"127.0.0.1": "ghe-token"})
+	defer func() {
+		viper.Set("github.base-url", "")
+		viper.Set("github.tokens", map[string]string{})
+	}()
+
+	for name, repo := range map[string]RepoConfDef{
+		"global":   {Org: "o", Repo: "r"},
+		"per-tool": {Org: "o", Repo: "r", BaseURL: perTool.URL + "/api/v3/"},
+	} {
+		ghh, err := NewFor(repo)
+		if err != nil {
+			t.Fatalf("NewFor returned error: %v", err)
+		}
+		data, err := ghh.FetchAllReleases(context.Background(), "ghetool-"+name, FetchOptions{Force: true, RepoConf: repo})
+		if err != nil {
+			t.Fatalf("FetchAllReleases returned error: %v", err)
+		}
+		if len(data.Releases) != 1 {
+			t.Fatalf("expected the release from the %s GitHub Enterprise, got %d", name, len(data.Releases))
+		}
+		// the token of github.com is not sent to other hosts
+		if auth[name] != "Bearer ghe-token" {
+			t.Fatalf("expected the token of the host to be used for %s, got %q", name, auth[name])
+		}
+	}
+
+	if _, err := NewFor(RepoConfDef{Org: "o", Repo: "r", BaseURL: "ftp://example.com"}); err == nil {
+		t.Fatalf("expected invalid base URL to fail")
+	}
+	if got := Token(publicHost); got != "public-token" {
+		t.Fatalf("expected GITHUB_TOKEN for github.com, got %q", got)
+	}
+	if got := Token("other.example.com"); got != "" {
+		t.Fatalf("expected no token for an unknown host, got %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+)
+
+// ErrRateLimited is returned when the GitHub API rate limit is exhausted for longer than vrsr waits for it.
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+const (
+	// defaultRateLimitWait is the longest wait for the rate limit to reset, unless "github.rate-limit-wait" is set.
+	defaultRateLimitWait = time.Minute
+	// secondaryRateLimitWait is the wait after a secondary rate limit without Retry-After, as GitHub recommends.
+	secondaryRateLimitWait = time.Minute
+	// maxRateLimitRetries is how many times a call is retried after waiting for the rate limit.
+	maxRateLimitRetries = 3
+)
+
+// sleep waits for the rate limit to reset, giving up when ctx is done. Replaced in tests.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying after err and when the limit resets,
+// ok is false when err is not a rate limit error.
+func rateLimitWait(err error, now time.Time) (wait time.Duration, reset time.Time, ok bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		reset = rateErr.Rate.Reset.Time
+		// a second of margin against clock skew
+		wait = max(reset.Sub(now)+time.Second, 0)
+		return wait, reset, true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		wait = secondaryRateLimitWait
+		if d := abuseErr.GetRetryAfter(); d > 0 {
+			wait = d
+		}
+		return wait, now.Add(wait), true
+	}
+	return 0, time.Time{}, false
+}
+
+// maxRateLimitWait returns the longest wait for the rate limit to reset, from the "github.rate-limit-wait" setting.
+func maxRateLimitWait() time.Duration {
+	if !viper.IsSet("github.rate-limit-wait") {
+		return defaultRateLimitWait
+	}
+	return viper.GetDuration("github.rate-limit-wait")
+}
+
+// retryRateLimited runs call, waiting for the rate limit to reset and retrying when the reset is near.
+// Rate limit errors it does not wait for are explained, the wait is abandoned when ctx is done.
+func (gh *GithubHelper) retryRateLimited(ctx context.Context, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := call()
+		wait, reset, ok := rateLimitWait(err, time.Now())
+		if !ok {
+			return err
+		}
+		if attempt >= maxRateLimitRetries || wait > maxRateLimitWait() {
+			return gh.rateLimitError(err, reset)
+		}
+		fmt.Fprintf(os.Stderr, "GitHub API rate limit reached, waiting %s for it to reset...\n", wait.Round(time.Second))
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// rateLimitError explains the rate limit error err, with when it resets and how to raise the limit.
+func (gh *GithubHelper) rateLimitError(err error, reset time.Time) error {
+	host := publicHost
+	if gh.Client != nil {
+		host = Host(gh.Client.BaseURL)
+	}
+	kind := "the"
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) && rateErr.Rate.Limit > 0 {
+		kind = fmt.Sprintf("the %d calls per hour", rateErr.Rate.Limit)
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		kind = "the secondary"
+	}
+	msg := fmt.Sprintf("%s rate limit of %s is exhausted until %s (%s)", kind, host, reset.Local().Format(time.TimeOnly), humanize.Time(reset))
+	if !gh.authenticated {
+		msg += ": log in with `vrsr auth login` to raise it to 5,000 calls per hour"
+	}
+	return fmt.Errorf("%w: %s", ErrRateLimited, msg)
+}
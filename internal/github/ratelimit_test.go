@@ -0,0 +1,131 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// pagedReposForTest serves releases by page, failing with err on the pages in fail
+type pagedReposForTest struct {
+	pages [][]*gh.RepositoryRelease
+	fail  map[int]error
+	calls []int
+}
+
+func (f *pagedReposForTest) ListReleases(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.RepositoryRelease, *gh.Response, error) {
+	f.calls = append(f.calls, opts.Page)
+	if err, ok := f.fail[opts.Page]; ok {
+		delete(f.fail, opts.Page)
+		return nil, nil, err
+	}
+	resp := &gh.Response{LastPage: len(f.pages)}
+	if opts.Page < len(f.pages) {
+		resp.NextPage = opts.Page + 1
+	}
+	return f.pages[opts.Page-1], resp, nil
+}
+
+func (f *pagedReposForTest) GetReleaseByTag(ctx context.Context, owner, repo, tag string) (*gh.RepositoryRelease, *gh.Response, error) {
+	return nil, nil, nil
+}
+
+func (f *pagedReposForTest) DownloadReleaseAsset(ctx context.Context, owner, repo string, assetID int64, httpClient *http.Client) (io.ReadCloser, string, error) {
+	return nil, "", nil
+}
+
+func rateLimitErr(reset time.Time) error {
+	return &gh.RateLimitError{Rate: gh.Rate{Limit: 60, Remaining: 0, Reset: gh.Timestamp{Time: reset}}, Message: "API rate limit exceeded"}
+}
+
+func releasePages() [][]*gh.RepositoryRelease {
+	return [][]*gh.RepositoryRelease{
+		{{TagName: gh.Ptr("v3.0.0")}, {TagName: gh.Ptr("v2.0.0")}},
+		{{TagName: gh.Ptr("v1.0.0")}},
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Now()
+	wait, reset, ok := rateLimitWait(rateLimitErr(now.Add(10*time.Second)), now)
+	if !ok || wait != 11*time.Second || !reset.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("unexpected wait %s until %s (%v)", wait, reset, ok)
+	}
+	retryAfter := 30 * time.Second
+	if wait, _, ok := rateLimitWait(&gh.AbuseRateLimitError{RetryAfter: &retryAfter}, now); !ok || wait != retryAfter {
+		t.Fatalf("expected to wait for Retry-After, got %s (%v)", wait, ok)
+	}
+	if wait, _, ok := rateLimitWait(&gh.AbuseRateLimitError{}, now); !ok || wait != secondaryRateLimitWait {
+		t.Fatalf("expected default secondary wait, got %s (%v)", wait, ok)
+	}
+	if _, _, ok := rateLimitWait(errors.New("boom"), now); ok {
+		t.Fatalf("expected other errors not to be rate limits")
+	}
+}
+
+func TestFetchAllReleases_WaitsForNearReset(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var waited []time.Duration
+	origSleep := sleep
+	sleep = func(_ context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		return nil
+	}
+	defer func() { sleep = origSleep }()
+
+	fake := &pagedReposForTest{pages: releasePages(), fail: map[int]error{2: rateLimitErr(time.Now().Add(5 * time.Second))}}
+	ghh := GithubHelper{Repos: fake}
+	data, err := ghh.FetchAllReleases(context.Background(), "waittool", FetchOptions{Force: true, RepoConf: RepoConfDef{Org: "o", Repo: "r"}})
+	if err != nil {
+		t.Fatalf("FetchAllReleases returned error: %v", err)
+	}
+	if len(data.Releases) != 3 || len(waited) != 1 || waited[0] > 6*time.Second {
+		t.Fatalf("expected one short wait and 3 releases, got %v and %d releases", waited, len(data.Releases))
+	}
+}
+
+func TestFetchAllReleases_ResumesAfterRateLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	origSleep := sleep
+	sleep = func(context.Context, time.Duration) error {
+		t.Fatalf("expected not to wait for a distant reset")
+		return nil
+	}
+	defer func() { sleep = origSleep }()
+	tool := "resumetool"
+	repo := RepoConfDef{Org: "o", Repo: "r"}
+
+	fake := &pagedReposForTest{pages: releasePages(), fail: map[int]error{2: rateLimitErr(time.Now().Add(time.Hour))}}
+	ghh := GithubHelper{Repos: fake}
+	_, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true, RepoConf: repo})
+	if !errors.Is(err, ErrRateLimited) || !strings.Contains(err.Error(), "vrsr auth login") || !strings.Contains(err.Error(), "run the command again") {
+		t.Fatalf("expected friendly rate limit error, got: %v", err)
+	}
+	if p, ok, err := utils.ReadPartialCache(tool); err != nil || !ok || p.NextPage != 2 || len(p.Releases) != 2 {
+		t.Fatalf("expected the first page to be kept, got %+v (%v, %v)", p, ok, err)
+	}
+
+	// a release published meanwhile shifts v2.0.0 to the second page
+	fake.pages[1] = append([]*gh.RepositoryRelease{{TagName: gh.Ptr("v2.0.0")}}, fake.pages[1]...)
+	fake.calls = nil
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{Force: true, RepoConf: repo})
+	if err != nil {
+		t.Fatalf("FetchAllReleases returned error: %v", err)
+	}
+	if len(fake.calls) != 1 || fake.calls[0] != 2 {
+		t.Fatalf("expected to resume from page 2, fetched pages %v", fake.calls)
+	}
+	if len(data.Releases) != 3 {
+		t.Fatalf("expected 3 distinct releases, got %d", len(data.Releases))
+	}
+	if _, ok, _ := utils.ReadPartialCache(tool); ok {
+		t.Fatalf("expected the partial progress to be removed")
+	}
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// backgroundRefreshInterval is how long a background refresh of the releases of a tool
+// keeps others from being started, so that a burst of commands starts only one.
+const backgroundRefreshInterval = time.Minute
+
+// startBackgroundRefresh starts the background refresh of the releases cache of the tool,
+// it is a variable so that tests can replace it.
+var startBackgroundRefresh = spawnRefresh
+
+// cachePolicy returns how long the releases cache of the tool is fresh, from the "<tool>.cache.ttl"
+// or "cache.ttl" settings (0 when it never expires), and whether an expired cache is served while
+// it is refreshed in the background, from the "<tool>.cache.stale-while-revalidate" or
+// "cache.stale-while-revalidate" settings.
+func cachePolicy(tool string) (time.Duration, bool, error) {
+	key := tool + ".cache.ttl"
+	if !viper.IsSet(key) {
+		key = "cache.ttl"
+	}
+	var ttl time.Duration
+	if s := viper.GetString(key); s != "" {
+		var err error
+		if ttl, err = utils.ParseAge(s); err != nil {
+			return 0, false, fmt.Errorf("invalid %s setting: %w", key, err)
+		}
+	}
+	key = tool + ".cache.stale-while-revalidate"
+	if !viper.IsSet(key) {
+		key = "cache.stale-while-revalidate"
+	}
+	return ttl, viper.GetBool(key), nil
+}
+
+// refreshInBackground refreshes the releases cache of the tool in a detached process,
+// unless another one was started recently.
+func refreshInBackground(tool string) {
+	cachePath, err := utils.GetCachePath(tool)
+	if err != nil {
+		return
+	}
+	marker := cachePath + ".refresh"
+	if fi, err := os.Stat(marker); err == nil && time.Since(fi.ModTime()) < backgroundRefreshInterval {
+		return
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to refresh the releases cache in background:", err)
+		return
+	}
+	if err := startBackgroundRefresh(tool); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to refresh the releases cache in background:", err)
+	}
+}
+
+// spawnRefresh runs `vrsr <tool> list-remote --force` detached from the current process,
+// with the same configuration file.
+func spawnRefresh(tool string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{tool, "list-remote", "--force"}
+	if cfg := viper.ConfigFileUsed(); cfg != "" {
+		args = append(args, "--config", cfg)
+	}
+	c := exec.Command(exe, args...)
+	detach(c)
+	if err := c.Start(); err != nil {
+		return err
+	}
+	// the refresh outlives this process, nobody waits for it
+	return c.Process.Release()
+}
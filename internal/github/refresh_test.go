@@ -0,0 +1,87 @@
+package github
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// countingReposForTest counts the calls listing the releases
+type countingReposForTest struct {
+	fakeReposForTest
+	lists int
+}
+
+func (f *countingReposForTest) ListReleases(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.RepositoryRelease, *gh.Response, error) {
+	f.lists++
+	return f.fakeReposForTest.ListReleases(ctx, owner, repo, opts)
+}
+
+func TestFetchAllReleases_CacheTTL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tool := "ttltool"
+	t.Cleanup(func() {
+		viper.Set(tool+".cache.ttl", nil)
+		viper.Set("cache.ttl", nil)
+		viper.Set(tool+".cache.stale-while-revalidate", nil)
+	})
+	if err := utils.WriteCache(tool, utils.ReleasesData{
+		Timestamp: time.Now().UTC().Add(-2 * time.Hour),
+		Releases:  []utils.Release{{Tag: "v1.0.0"}},
+		Complete:  true,
+	}); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	fake := &countingReposForTest{fakeReposForTest: fakeReposForTest{releases: []*gh.RepositoryRelease{
+		{TagName: gh.Ptr("v1.1.0")},
+		{TagName: gh.Ptr("v1.0.0")},
+	}}}
+	ghh := GithubHelper{Repos: fake}
+	var spawned []string
+	startBackgroundRefresh = func(tool string) error {
+		spawned = append(spawned, tool)
+		return nil
+	}
+	t.Cleanup(func() { startBackgroundRefresh = spawnRefresh })
+
+	// without a TTL the cache never expires
+	data, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{})
+	if err != nil || len(data.Releases) != 1 || fake.lists != 0 {
+		t.Fatalf("expected the cache to be served, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+
+	// the global TTL is overridden by the tool one
+	viper.Set("cache.ttl", "1d")
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err != nil || len(data.Releases) != 1 || fake.lists != 0 {
+		t.Fatalf("expected the cache to be fresh, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+	viper.Set(tool+".cache.stale-while-revalidate", true)
+	viper.Set(tool+".cache.ttl", "1h")
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err != nil || len(data.Releases) != 1 || fake.lists != 0 {
+		t.Fatalf("expected the stale cache to be served, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+	if len(spawned) != 1 || spawned[0] != tool {
+		t.Fatalf("expected one background refresh, got %v", spawned)
+	}
+	// a refresh was just started
+	if _, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err != nil || len(spawned) != 1 {
+		t.Fatalf("expected no other background refresh, got %v (%v)", spawned, err)
+	}
+
+	viper.Set(tool+".cache.stale-while-revalidate", false)
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err != nil || len(data.Releases) != 2 || fake.lists != 1 {
+		t.Fatalf("expected the expired cache to be refreshed, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+	if data, err = ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err != nil || fake.lists != 1 {
+		t.Fatalf("expected the refreshed cache to be fresh, got %d lists (%v)", fake.lists, err)
+	}
+
+	viper.Set(tool+".cache.ttl", "soon")
+	if _, err := ghh.FetchAllReleases(context.Background(), tool, FetchOptions{}); err == nil {
+		t.Fatalf("expected an error for an invalid TTL")
+	}
+}
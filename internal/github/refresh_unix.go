@@ -0,0 +1,13 @@
+//go:build !windows
+
+package github
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs the command in its own session, so that it survives the terminal of vrsr.
+func detach(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
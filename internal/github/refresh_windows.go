@@ -0,0 +1,16 @@
+//go:build windows
+
+package github
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs the command without a console, so that it survives the console of vrsr.
+func detach(c *exec.Cmd) {
+	const detachedProcess = 0x00000008
+	c.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+}
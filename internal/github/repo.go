@@ -0,0 +1,171 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// RepoConfDef describes where a tool is released and how its binary is packaged.
+//
+// DownloadURL, AssetPattern, BinaryPath, ChecksumURL and ChecksumAsset are text/template strings rendered
+// with TemplateData, e.g. "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz".
+type RepoConfDef struct {
+	Org  string
+	Repo string
+	// DownloadURL, when set, is used to download the binary instead of the GitHub release assets.
+	DownloadURL string
+	// AssetPattern is the name of the GitHub release asset to download.
+	// When empty the asset is looked up as "<tool>-<os>-<arch>".
+	AssetPattern string
+	// ArchiveType is the archive format of the download ("", "tar.gz" or "zip").
+	ArchiveType string
+	// BinaryPath is the path of the binary inside the archive.
+	BinaryPath string
+	// ChecksumURL is the URL of the SHA-256 checksum file for DownloadURL.
+	// When empty, "<url>.sha256" and "<url>.sha256sum" are tried.
+	ChecksumURL string
+	// ChecksumAsset is the name of the release asset holding the SHA-256 checksum.
+	// When empty, the asset digest and the usual checksum assets are tried.
+	ChecksumAsset string
+	// BaseURL and UploadURL are the API endpoints of the GitHub Enterprise instance hosting the repository.
+	// When empty, the "github.base-url" and "github.upload-url" settings apply, then github.com.
+	BaseURL   string
+	UploadURL string
+}
+
+// Source identifies the repository the releases come from as "<host>/<org>/<repo>",
+// e.g. "github.com/kubernetes/kubernetes". It is empty when the repository is not set.
+func (rc RepoConfDef) Source() string {
+	if rc.Org == "" || rc.Repo == "" {
+		return ""
+	}
+	baseURL := rc.BaseURL
+	if baseURL == "" {
+		baseURL = viper.GetString("github.base-url")
+	}
+	host := publicHost
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return ""
+		}
+		host = Host(u)
+	}
+	return path.Join(host, strings.ToLower(rc.Org), strings.ToLower(rc.Repo))
+}
+
+// TemplateData holds the values available to the RepoConfDef templates.
+type TemplateData struct {
+	Tool    string
+	Version string
+	OS      string
+	Arch    string
+}
+
+var templateFuncs = template.FuncMap{
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trimv": func(s string) string { return strings.TrimPrefix(s, "v") },
+}
+
+// NewTemplateData returns the template values for the given tool version on the current OS/ARCH.
+func NewTemplateData(tool, version string) TemplateData {
+	return TemplateData{
+		Tool:    tool,
+		Version: version,
+		OS:      strings.ToLower(runtime.GOOS),
+		Arch:    strings.ToLower(runtime.GOARCH),
+	}
+}
+
+// NewPlatformTemplateData returns the template values for the given tool version on
+// the platform, formatted as "os/arch".
+func NewPlatformTemplateData(tool, version, platform string) (TemplateData, error) {
+	goos, goarch, ok := strings.Cut(strings.ToLower(platform), "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return TemplateData{}, fmt.Errorf("invalid platform %q, expected os/arch", platform)
+	}
+	return TemplateData{Tool: tool, Version: version, OS: goos, Arch: goarch}, nil
+}
+
+// Render executes the given template string against data.
+func Render(tmpl string, data TemplateData) (string, error) {
+	t, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", tmpl, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", tmpl, err)
+	}
+	return buf.String(), nil
+}
+
+// Validate checks that the templates of the configuration are well formed.
+func (rc RepoConfDef) Validate() error {
+	if rc.Org == "" || rc.Repo == "" {
+		return fmt.Errorf("org and repo are required")
+	}
+	switch rc.ArchiveType {
+	case utils.ArchiveNone, utils.ArchiveTarGz, utils.ArchiveZip:
+	default:
+		return fmt.Errorf("unsupported archive type %q", rc.ArchiveType)
+	}
+	for _, u := range []string{rc.BaseURL, rc.UploadURL} {
+		if err := validateAPIURL(u); err != nil {
+			return err
+		}
+	}
+	data := NewTemplateData("tool", "v0.0.0")
+	for _, tmpl := range []string{rc.DownloadURL, rc.AssetPattern, rc.BinaryPath, rc.ChecksumURL, rc.ChecksumAsset} {
+		if _, err := Render(tmpl, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveSpec returns the archive description used to extract the binary for the given version.
+func (rc RepoConfDef) ArchiveSpec(data TemplateData) (utils.ArchiveSpec, error) {
+	binaryPath, err := Render(rc.BinaryPath, data)
+	if err != nil {
+		return utils.ArchiveSpec{}, err
+	}
+	return utils.ArchiveSpec{Type: rc.ArchiveType, BinaryPath: binaryPath}, nil
+}
+
+// DownloadChecksum returns the published SHA-256 of the artifact at dlURL.
+func (rc RepoConfDef) DownloadChecksum(ctx context.Context, data TemplateData, dlURL string) (string, error) {
+	fileName := path.Base(dlURL)
+	if rc.ChecksumURL != "" {
+		checksumURL, err := Render(rc.ChecksumURL, data)
+		if err != nil {
+			return "", err
+		}
+		return utils.FetchChecksum(ctx, checksumURL, fileName)
+	}
+	for _, suffix := range []string{".sha256", ".sha256sum"} {
+		sum, err := utils.FetchChecksum(ctx, dlURL+suffix, fileName)
+		if errors.Is(err, utils.ErrChecksumNotFound) {
+			continue
+		}
+		return sum, err
+	}
+	return "", fmt.Errorf("%w for %s", utils.ErrChecksumNotFound, dlURL)
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestRender_Functions(t *testing.T) {
+	data := TemplateData{Tool: "k9s", Version: "v0.32.5", OS: "darwin", Arch: "arm64"}
+	got, err := Render("{{.Tool}}_{{trimv .Version}}_{{.OS | title}}_{{.Arch | upper}}.tar.gz", data)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if got != "k9s_0.32.5_Darwin_ARM64.tar.gz" {
+		t.Fatalf("unexpected render result: %s", got)
+	}
+	if _, err := Render("{{.Missing}}", data); err == nil {
+		t.Fatalf("expected error for unknown template field")
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	sum := "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bare/tool.sha256":
+			_, _ = w.Write([]byte(sum + "\n"))
+		case "/sums.txt":
+			_, _ = w.Write([]byte(sum + "  other.tar.gz\n" + sum + "  *tool.tar.gz\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	data := NewTemplateData("tool", "v1.0.0")
+
+	got, err := RepoConfDef{}.DownloadChecksum(context.Background(), data, srv.URL+"/bare/tool")
+	if err != nil || got != sum {
+		t.Fatalf("expected sidecar checksum, got %q (%v)", got, err)
+	}
+
+	rc := RepoConfDef{ChecksumURL: srv.URL + "/sums.txt"}
+	got, err = rc.DownloadChecksum(context.Background(), data, srv.URL+"/dl/tool.tar.gz")
+	if err != nil || got != sum {
+		t.Fatalf("expected aggregated checksum, got %q (%v)", got, err)
+	}
+
+	_, err = RepoConfDef{}.DownloadChecksum(context.Background(), data, srv.URL+"/missing/tool")
+	if !errors.Is(err, utils.ErrChecksumNotFound) {
+		t.Fatalf("expected ErrChecksumNotFound, got %v", err)
+	}
+}
+
+func TestRepoConfDef_Source(t *testing.T) {
+	t.Cleanup(func() { viper.Set("github.base-url", nil) })
+	if got := (RepoConfDef{Org: "Kubernetes", Repo: "kubernetes"}).Source(); got != "github.com/kubernetes/kubernetes" {
+		t.Fatalf("unexpected source: %s", got)
+	}
+	if got := (RepoConfDef{Org: "o", Repo: "r", BaseURL: "https://ghe.example.com/api/v3/"}).Source(); got != "ghe.example.com/o/r" {
+		t.Fatalf("unexpected enterprise source: %s", got)
+	}
+	viper.Set("github.base-url", "https://ghe.example.com/api/v3/")
+	if got := (RepoConfDef{Org: "o", Repo: "r"}).Source(); got != "ghe.example.com/o/r" {
+		t.Fatalf("expected the global base URL to apply, got %s", got)
+	}
+	if got := (RepoConfDef{DownloadURL: "https://example.com/tool"}).Source(); got != "" {
+		t.Fatalf("expected no source without a repository, got %s", got)
+	}
+}
+
+func TestFetchAllReleases_SharedSourceCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := RepoConfDef{Org: "kubernetes", Repo: "kubernetes"}
+	utils.RegisterReleasesSource("kubeadmtest", repo.Source)
+	utils.RegisterReleasesSource("kubelettest", repo.Source)
+
+	// the cache kept under the tool name is adopted
+	legacy := filepath.Join(home, ".vrsr", "kubeadmtest-releases.json")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o755); err != nil {
+		t.Fatalf("failed to create cache dir: %v", err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"version":1,"timestamp":"2025-01-01T00:00:00Z","complete":true,"releases":[{"tag_name":"v1.30.0"}]}`), 0o644); err != nil {
+		t.Fatalf("failed to write legacy cache: %v", err)
+	}
+	fake := &countingReposForTest{fakeReposForTest: fakeReposForTest{releases: []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.31.0")}, {TagName: gh.Ptr("v1.30.0")}}}}
+	ghh := GithubHelper{Repos: fake}
+	data, err := ghh.FetchAllReleases(context.Background(), "kubeadmtest", FetchOptions{RepoConf: repo})
+	if err != nil || len(data.Releases) != 1 || fake.lists != 0 {
+		t.Fatalf("expected the legacy cache to be used, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("expected the legacy cache to be moved, stat error: %v", err)
+	}
+
+	// a refresh for one tool serves the other
+	if _, err := ghh.FetchAllReleases(context.Background(), "kubeadmtest", FetchOptions{Force: true, RepoConf: repo}); err != nil || fake.lists != 1 {
+		t.Fatalf("expected one refresh, got %d lists (%v)", fake.lists, err)
+	}
+	data, err = ghh.FetchAllReleases(context.Background(), "kubelettest", FetchOptions{RepoConf: repo})
+	if err != nil || len(data.Releases) != 2 || fake.lists != 1 {
+		t.Fatalf("expected the shared cache to be used, got %d releases, %d lists (%v)", len(data.Releases), fake.lists, err)
+	}
+	cachePath, err := utils.GetCachePath("kubelettest")
+	if err != nil || cachePath != filepath.Join(home, ".vrsr", "cache", "github.com", "kubernetes", "kubernetes", "releases.json") {
+		t.Fatalf("unexpected cache path %s (%v)", cachePath, err)
+	}
+}
@@ -0,0 +1,166 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/viper"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// ErrNotMirrored is returned when the mirror does not serve the requested tool or version.
+var ErrNotMirrored = errors.New("not available on the mirror")
+
+// Version is an installed tool version as served by the mirror.
+type Version struct {
+	utils.Manifest
+	// BinarySHA256 is the checksum of the served binary, SHA256 being the one of the upstream artifact.
+	BinarySHA256 string `json:"binary_sha256"`
+}
+
+// URL returns the base URL of the mirror set with the "mirror" setting, empty when there is none.
+func URL() string {
+	return strings.TrimRight(viper.GetString("mirror"), "/")
+}
+
+// IndexPath returns the path of the index of the mirrored tools.
+func IndexPath() string {
+	return "/v1/tools"
+}
+
+// ReleasesPath returns the path of the releases cache of the tool.
+func ReleasesPath(tool string) string {
+	return path.Join(IndexPath(), tool, "releases.json")
+}
+
+// ManifestPath returns the path of the Version of the tool version for the platform ("os/arch").
+func ManifestPath(tool, tag, platform string) string {
+	return path.Join(IndexPath(), tool, "versions", tag, platform, "manifest.json")
+}
+
+// BinaryPath returns the path of the binary of the tool version for the platform ("os/arch").
+func BinaryPath(tool, tag, platform string) string {
+	return path.Join(IndexPath(), tool, "versions", tag, platform, "binary")
+}
+
+// FetchReleases returns the releases of the tool served by the mirror at base.
+// Releases cached in a schema newer than the one known to this vrsr count as not mirrored.
+func FetchReleases(ctx context.Context, base, tool string) (utils.ReleasesData, error) {
+	var data utils.ReleasesData
+	if err := getJSON(ctx, base+ReleasesPath(tool), &data); err != nil {
+		return data, err
+	}
+	if data.Version > utils.CacheVersion {
+		return utils.ReleasesData{}, fmt.Errorf("%w: releases cache version %d of %s is not supported", ErrNotMirrored, data.Version, tool)
+	}
+	return data, nil
+}
+
+// InstallOptions tunes the verification done by Install.
+type InstallOptions struct {
+	// Verify checks the binary against the checksum published by the mirror.
+	Verify bool
+	// Checksum is the expected SHA-256 of the upstream artifact (e.g. from the lockfile). The binary
+	// served must be that artifact, as the mirror does not keep the archives binaries are extracted from.
+	Checksum string
+}
+
+// Install downloads the binary of the tool version for the current platform from the mirror
+// at base and stores it into vrsPath. The download is abandoned when ctx is done.
+// With a Checksum, a binary extracted from an archive cannot be verified and counts as not mirrored.
+func Install(ctx context.Context, base, tool, tag, vrsPath string, opts InstallOptions) error {
+	platform := project.Platform(runtime.GOOS, runtime.GOARCH)
+	var v Version
+	if err := getJSON(ctx, base+ManifestPath(tool, tag, platform), &v); err != nil {
+		return err
+	}
+	checksum := ""
+	switch {
+	case opts.Checksum != "":
+		if v.BinarySHA256 != opts.Checksum {
+			// the expected artifact may be the archive the binary was extracted from, only available upstream
+			return fmt.Errorf("%w: the binary of %s %s is not the artifact with SHA-256 %s", ErrNotMirrored, tool, tag, opts.Checksum)
+		}
+		// the downloaded binary is verified against the expected checksum itself, not the one the mirror claims
+		checksum = opts.Checksum
+	case opts.Verify:
+		if v.BinarySHA256 == "" {
+			return fmt.Errorf("%w: the mirror publishes no checksum for %s %s", utils.ErrChecksumNotFound, tool, tag)
+		}
+		checksum = v.BinarySHA256
+	}
+
+	srcURL := base + BinaryPath(tool, tag, platform)
+	resp, err := get(ctx, srcURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	bar := progressbar.DefaultBytes(resp.ContentLength, "Downloading from mirror...")
+	if err := utils.SaveBinary(io.TeeReader(resp.Body, bar), vrsPath, utils.Artifact{
+		Tool:      tool,
+		Version:   tag,
+		SourceURL: srcURL,
+		Checksum:  checksum,
+	}); err != nil {
+		return err
+	}
+	if opts.Checksum != "" {
+		// the binary is the upstream artifact, SaveBinary recorded its verified checksum
+		return nil
+	}
+	// keep the checksum of the upstream artifact, as a direct install would
+	m, err := utils.ReadManifest(vrsPath, tool, tag)
+	if err != nil {
+		return err
+	}
+	m.SHA256 = v.SHA256
+	return utils.WriteManifest(vrsPath, m)
+}
+
+// getJSON decodes the JSON document at url into v.
+func getJSON(ctx context.Context, url string, v any) error {
+	resp, err := get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response from mirror %s: %w", url, err)
+	}
+	return nil
+}
+
+// get requests url from the mirror, reporting a 404 as ErrNotMirrored.
+func get(ctx context.Context, url string) (*http.Response, error) {
+	if err := utils.CheckOnline("access the mirror " + url); err != nil {
+		return nil, err
+	}
+	resp, err := utils.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach mirror: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotMirrored, url)
+	default:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("bad status from mirror %s: %s", url, resp.Status)
+	}
+}
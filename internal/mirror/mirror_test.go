@@ -0,0 +1,157 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v78/github"
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// sha256("ok")
+const okSum = "2689367b205c16ce32ed4200942b8b8b1e262dfc70d9bc9fbc77c49699a4f1df"
+
+// cacheReleases stores the releases of the tool in its cache, as a complete fetch does.
+func cacheReleases(t *testing.T, tool string, rels []*gh.RepositoryRelease) {
+	t.Helper()
+	releases, notes := utils.CompactReleases(rels)
+	if err := utils.WriteCache(tool, utils.ReleasesData{Timestamp: time.Now().UTC(), Releases: releases, Complete: true}); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	if err := utils.SaveReleaseNotes(tool, notes); err != nil {
+		t.Fatalf("failed to save release notes: %v", err)
+	}
+}
+
+// newTestMirror serves mirrortool v1.0.0, installed from an artifact whose SHA-256 is artifactSum
+func newTestMirror(t *testing.T, artifactSum string) *httptest.Server {
+	t.Helper()
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	vrsPath := filepath.Join(td, "server")
+	if err := utils.SaveBinary(strings.NewReader("ok"), vrsPath, utils.Artifact{Tool: "mirrortool", Version: "v1.0.0"}); err != nil {
+		t.Fatalf("failed to install binary: %v", err)
+	}
+	m, err := utils.ReadManifest(vrsPath, "mirrortool", "v1.0.0")
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	m.SHA256 = artifactSum
+	if err := utils.WriteManifest(vrsPath, m); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	cacheReleases(t, "mirrortool", []*gh.RepositoryRelease{{TagName: gh.Ptr("v1.0.0")}})
+	srv := httptest.NewServer(NewHandler(vrsPath, []string{"mirrortool", "othertool"}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMirror_ReleasesAndIndex(t *testing.T) {
+	srv := newTestMirror(t, "")
+
+	data, err := FetchReleases(context.Background(), srv.URL, "mirrortool")
+	if err != nil || len(data.Releases) != 1 || data.Releases[0].Tag != "v1.0.0" {
+		t.Fatalf("unexpected releases %+v (%v)", data, err)
+	}
+	if _, err := FetchReleases(context.Background(), srv.URL, "othertool"); !errors.Is(err, ErrNotMirrored) {
+		t.Fatalf("expected tool without cache not to be mirrored, got: %v", err)
+	}
+	if _, err := FetchReleases(context.Background(), srv.URL, "unknown"); !errors.Is(err, ErrNotMirrored) {
+		t.Fatalf("expected unknown tool not to be mirrored, got: %v", err)
+	}
+
+	var idx Index
+	if err := getJSON(context.Background(), srv.URL+IndexPath(), &idx); err != nil {
+		t.Fatalf("failed to get index: %v", err)
+	}
+	if idx.Platform != project.Platform(runtime.GOOS, runtime.GOARCH) || len(idx.Tools) != 2 ||
+		idx.Tools[0].Tool != "mirrortool" || idx.Tools[0].CacheTimestamp == nil || len(idx.Tools[0].Versions) != 1 ||
+		idx.Tools[1].CacheTimestamp != nil {
+		t.Fatalf("unexpected index: %+v", idx)
+	}
+}
+
+func TestMirror_Install(t *testing.T) {
+	artifactSum := strings.Repeat("a", 64)
+	srv := newTestMirror(t, artifactSum)
+	vrsPath := filepath.Join(t.TempDir(), "client")
+
+	// the lockfile expects the archive the binary was extracted from, which only upstream has
+	err := Install(context.Background(), srv.URL, "mirrortool", "v1.0.0", vrsPath, InstallOptions{Verify: true, Checksum: artifactSum})
+	if !errors.Is(err, ErrNotMirrored) {
+		t.Fatalf("expected the binary not to be mirrored for an archive, got: %v", err)
+	}
+	if err := Install(context.Background(), srv.URL, "mirrortool", "v1.0.0", vrsPath, InstallOptions{Verify: true}); err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vrsPath, "mirrortool", "mirrortool-v1.0.0"))
+	if err != nil || string(content) != "ok" {
+		t.Fatalf("expected the binary to be installed, got %q (%v)", content, err)
+	}
+	m, err := utils.ReadManifest(vrsPath, "mirrortool", "v1.0.0")
+	if err != nil || m.SHA256 != artifactSum || !strings.HasPrefix(m.SourceURL, srv.URL) {
+		t.Fatalf("unexpected manifest %+v (%v)", m, err)
+	}
+
+	if err := Install(context.Background(), srv.URL, "mirrortool", "v2.0.0", vrsPath, InstallOptions{}); !errors.Is(err, ErrNotMirrored) {
+		t.Fatalf("expected missing version not to be mirrored, got: %v", err)
+	}
+}
+
+func TestMirror_InstallVerifiesLockedChecksum(t *testing.T) {
+	// the upstream artifact is the binary itself
+	srv := newTestMirror(t, okSum)
+	vrsPath := filepath.Join(t.TempDir(), "client")
+	if err := Install(context.Background(), srv.URL, "mirrortool", "v1.0.0", vrsPath, InstallOptions{Checksum: okSum}); err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	m, err := utils.ReadManifest(vrsPath, "mirrortool", "v1.0.0")
+	if err != nil || m.SHA256 != okSum {
+		t.Fatalf("unexpected manifest %+v (%v)", m, err)
+	}
+
+	// a mirror serving another binary than the one it claims is caught
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/binary") {
+			_, _ = w.Write([]byte("ko"))
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(tampered.Close)
+	err = Install(context.Background(), tampered.URL, "mirrortool", "v1.0.0", filepath.Join(t.TempDir(), "client"), InstallOptions{Checksum: okSum})
+	if !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got: %v", err)
+	}
+}
+
+func TestServer_OnlyServesInstalledBinaries(t *testing.T) {
+	srv := newTestMirror(t, okSum)
+	platform := project.Platform(runtime.GOOS, runtime.GOARCH)
+	other := "plan9/arm"
+	for p, want := range map[string]int{
+		BinaryPath("mirrortool", "v1.0.0", platform):                          http.StatusOK,
+		BinaryPath("mirrortool", "v1.0.0", other):                             http.StatusNotFound,
+		BinaryPath("othertool", "v1.0.0", platform):                           http.StatusNotFound,
+		BinaryPath("mirrortool", "..", platform):                              http.StatusNotFound,
+		"/v1/tools/mirrortool/versions/..%2F..%2Fetc/" + platform + "/binary": http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + p)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", p, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s: expected status %d, got %d", p, want, resp.StatusCode)
+		}
+	}
+}
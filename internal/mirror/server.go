@@ -0,0 +1,204 @@
+package mirror
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// Index lists what the mirror serves.
+type Index struct {
+	// Platform is the one of the served binaries, the one of the mirror host.
+	Platform string      `json:"platform"`
+	Tools    []ToolIndex `json:"tools"`
+}
+
+// ToolIndex lists what the mirror serves for a tool.
+type ToolIndex struct {
+	Tool string `json:"tool"`
+	// CacheTimestamp is the age of the served releases, nil when there are none.
+	CacheTimestamp *time.Time `json:"cache_timestamp"`
+	Versions       []string   `json:"versions"`
+}
+
+// server serves the releases caches and the installed versions of the tools
+type server struct {
+	vrsPath  string
+	tools    []string
+	platform string
+
+	mu sync.Mutex
+	// sums holds the checksums of the served binaries by path, so they are hashed once.
+	sums map[string]binarySum
+}
+
+// binarySum is the checksum of a binary, valid as long as its size and modification time stay the same.
+type binarySum struct {
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+// NewHandler returns the handler serving the releases cache and the versions installed into
+// vrsPath of the given tools, following the layout of IndexPath, ReleasesPath, ManifestPath and BinaryPath.
+func NewHandler(vrsPath string, tools []string) http.Handler {
+	s := &server{
+		vrsPath:  vrsPath,
+		tools:    slices.Sorted(slices.Values(tools)),
+		platform: project.Platform(runtime.GOOS, runtime.GOARCH),
+		sums:     map[string]binarySum{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+IndexPath(), s.index)
+	mux.HandleFunc("GET "+ReleasesPath("{tool}"), s.releases)
+	mux.HandleFunc("GET "+ManifestPath("{tool}", "{tag}", "{os}/{arch}"), s.manifest)
+	mux.HandleFunc("GET "+BinaryPath("{tool}", "{tag}", "{os}/{arch}"), s.binary)
+	return mux
+}
+
+// index serves the Index of the mirror
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	idx := Index{Platform: s.platform, Tools: []ToolIndex{}}
+	for _, tool := range s.tools {
+		ti := ToolIndex{Tool: tool, Versions: []string{}}
+		if data, err := utils.ReadFromCache(tool, 0); err == nil && len(data.Releases) > 0 {
+			ti.CacheTimestamp = &data.Timestamp
+		}
+		manifests, err := utils.ReadManifests(s.vrsPath, tool)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, m := range manifests {
+			ti.Versions = append(ti.Versions, m.Tag)
+		}
+		idx.Tools = append(idx.Tools, ti)
+	}
+	writeJSON(w, idx)
+}
+
+// releases serves the releases cache of a tool
+func (s *server) releases(w http.ResponseWriter, r *http.Request) {
+	tool := r.PathValue("tool")
+	if !slices.Contains(s.tools, tool) {
+		http.NotFound(w, r)
+		return
+	}
+	cachePath, err := utils.GetCachePath(tool)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serveFile(w, r, cachePath)
+}
+
+// manifest serves the Version of an installed tool version
+func (s *server) manifest(w http.ResponseWriter, r *http.Request) {
+	m, binPath, ok := s.installed(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	sum, err := s.binarySum(binPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, Version{Manifest: m, BinarySHA256: sum})
+}
+
+// binarySum returns the SHA-256 of the binary at binPath, only hashing it again when it changed.
+func (s *server) binarySum(binPath string) (string, error) {
+	fi, err := os.Stat(binPath)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	cached, ok := s.sums[binPath]
+	s.mu.Unlock()
+	if ok && cached.size == fi.Size() && cached.modTime.Equal(fi.ModTime()) {
+		return cached.sha256, nil
+	}
+	f, err := os.Open(binPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	sum, err := utils.HashReader(f)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.sums[binPath] = binarySum{size: fi.Size(), modTime: fi.ModTime(), sha256: sum}
+	s.mu.Unlock()
+	return sum, nil
+}
+
+// binary serves the binary of an installed tool version
+func (s *server) binary(w http.ResponseWriter, r *http.Request) {
+	_, binPath, ok := s.installed(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	serveFile(w, r, binPath)
+}
+
+// installed returns the manifest and the binary path of the tool version requested,
+// if it is installed for the requested platform
+func (s *server) installed(r *http.Request) (utils.Manifest, string, bool) {
+	tool, tag := r.PathValue("tool"), r.PathValue("tag")
+	platform := project.Platform(r.PathValue("os"), r.PathValue("arch"))
+	if !slices.Contains(s.tools, tool) || platform != s.platform || tag == "." || tag == ".." || filepath.Base(tag) != tag {
+		return utils.Manifest{}, "", false
+	}
+	m, err := utils.ReadManifest(s.vrsPath, tool, tag)
+	if err != nil {
+		return m, "", false
+	}
+	binPath := filepath.Join(s.vrsPath, tool, m.Binary)
+	if _, err := os.Stat(binPath); err != nil {
+		return m, "", false
+	}
+	return m, binPath, true
+}
+
+// serveFile serves the file at p, or a 404 if there is none
+func serveFile(w http.ResponseWriter, r *http.Request, p string) {
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, filepath.Base(p), fi.ModTime(), f)
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,119 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// LockFileName is the name of the lockfile, stored next to the project file.
+const LockFileName = "vrsr.lock"
+
+// DefaultPlatforms are the OS/ARCH pairs recorded in the lockfile by default.
+var DefaultPlatforms = []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64"}
+
+// Lock records the exact artifacts of the tool versions resolved from the project file,
+// so that every machine installs byte-identical binaries.
+type Lock struct {
+	// Path is the absolute path of the lockfile.
+	Path  string                `yaml:"-"`
+	Tools map[string]LockedTool `yaml:"tools"`
+}
+
+// LockedTool is the resolved version of a tool.
+type LockedTool struct {
+	// Version is the version as pinned in the project file (e.g. "1.30").
+	Version string `yaml:"version"`
+	// Tag is the exact version it resolved to (e.g. "v1.30.4").
+	Tag string `yaml:"tag"`
+	// Platforms maps "os/arch" to the artifact for that platform.
+	Platforms map[string]LockedArtifact `yaml:"platforms"`
+}
+
+// LockedArtifact is the download of a tool version for a platform.
+type LockedArtifact struct {
+	URL    string `yaml:"url"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// Platform returns the lockfile key of the given OS/ARCH pair.
+func Platform(goos, goarch string) string {
+	return goos + "/" + goarch
+}
+
+// LockPath returns the path of the lockfile belonging to the project file.
+func LockPath(projectFile string) string {
+	return filepath.Join(filepath.Dir(projectFile), LockFileName)
+}
+
+// LoadLock reads the lockfile at the given path. A nil Lock is returned when it does not exist.
+func LoadLock(path string) (*Lock, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+	l := &Lock{}
+	if err := yaml.Unmarshal(content, l); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if l.Tools == nil {
+		l.Tools = map[string]LockedTool{}
+	}
+	l.Path = path
+	return l, nil
+}
+
+// FindLock returns the lockfile of the nearest project, if any.
+func FindLock() (*Lock, error) {
+	f, err := FindFromWd()
+	if err != nil || f == nil {
+		return nil, err
+	}
+	return LoadLock(LockPath(f.Path))
+}
+
+// Save writes the lockfile to its path.
+func (l *Lock) Save() error {
+	content, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	header := []byte("# Generated by `vrsr lock update`, do not edit by hand.\n")
+	return os.WriteFile(l.Path, append(header, content...), 0644)
+}
+
+// Tool returns the locked version of the tool, if any.
+func (l *Lock) Tool(tool string) (LockedTool, bool) {
+	if l == nil {
+		return LockedTool{}, false
+	}
+	lt, ok := l.Tools[tool]
+	return lt, ok
+}
+
+// Locked returns the tag locked for the tool, provided the lockfile was generated
+// for the given pinned version.
+func (l *Lock) Locked(tool, pinned string) (string, bool) {
+	lt, ok := l.Tool(tool)
+	if !ok || lt.Version != pinned {
+		return "", false
+	}
+	return lt.Tag, true
+}
+
+// PlatformNames returns the platforms recorded for the tool, sorted.
+func (lt LockedTool) PlatformNames() []string {
+	names := make([]string, 0, len(lt.Platforms))
+	for name := range lt.Platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
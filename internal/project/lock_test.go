@@ -0,0 +1,68 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLock_SaveAndLoad(t *testing.T) {
+	td := t.TempDir()
+	path := LockPath(filepath.Join(td, FileName))
+	if l, err := LoadLock(path); err != nil || l != nil {
+		t.Fatalf("expected no lockfile, got %+v, %v", l, err)
+	}
+
+	l := &Lock{Path: path, Tools: map[string]LockedTool{
+		"kubectl": {
+			Version: "1.30",
+			Tag:     "v1.30.4",
+			Platforms: map[string]LockedArtifact{
+				"linux/amd64":  {URL: "https://example.com/linux", SHA256: "abc"},
+				"darwin/arm64": {URL: "https://example.com/darwin", SHA256: "def"},
+			},
+		},
+	}}
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	got, err := LoadLock(path)
+	if err != nil {
+		t.Fatalf("LoadLock returned error: %v", err)
+	}
+	lt, ok := got.Tool("kubectl")
+	if !ok || lt.Tag != "v1.30.4" || lt.Platforms["darwin/arm64"].SHA256 != "def" {
+		t.Fatalf("unexpected locked tool %+v", lt)
+	}
+	if names := lt.PlatformNames(); len(names) != 2 || names[0] != "darwin/arm64" {
+		t.Fatalf("unexpected platforms %v", names)
+	}
+	if tag, ok := got.Locked("kubectl", "1.30"); !ok || tag != "v1.30.4" {
+		t.Fatalf("expected 1.30 to be locked to v1.30.4, got %q", tag)
+	}
+	// the pin changed since the lockfile was generated
+	if _, ok := got.Locked("kubectl", "1.31"); ok {
+		t.Fatalf("expected a stale lock to be ignored")
+	}
+}
+
+func TestPinnedVersion_PrefersLockedTag(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, FileName), []byte("tools:\n  kubectl: \"1.30\"\n  helm: v3.14.0\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	l := &Lock{Path: filepath.Join(td, LockFileName), Tools: map[string]LockedTool{
+		"kubectl": {Version: "1.30", Tag: "v1.30.4"},
+	}}
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	t.Chdir(td)
+
+	if vrs, _ := PinnedVersion("kubectl"); vrs != "v1.30.4" {
+		t.Fatalf("expected locked tag, got %q", vrs)
+	}
+	if vrs, _ := PinnedVersion("helm"); vrs != "v3.14.0" {
+		t.Fatalf("expected pinned version for unlocked tool, got %q", vrs)
+	}
+}
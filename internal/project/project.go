@@ -0,0 +1,126 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FileName is the name of the project file pinning tool versions.
+const FileName = ".vrsr.yaml"
+
+// File is a project file pinning tool versions, e.g.:
+//
+//	tools:
+//	  kubectl: v1.30.4
+//	  helm: v3.14.0
+type File struct {
+	// Path is the absolute path of the project file.
+	Path string
+	// Tools maps tool names to the pinned version.
+	Tools map[string]string
+}
+
+// Find walks up from dir looking for the nearest project file.
+// A nil File is returned when there is none.
+func Find(dir string) (*File, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p := filepath.Join(dir, FileName)
+		if _, err := os.Stat(p); err == nil {
+			return Load(p)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// reached the filesystem root
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// FindFromWd looks for the nearest project file starting from the working directory.
+func FindFromWd() (*File, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return Find(wd)
+}
+
+// Load reads the project file at the given path.
+func Load(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file %s: %w", path, err)
+	}
+	// versions are decoded as plain strings, so that e.g. "1.30" is not read as a float
+	var data struct {
+		Tools map[string]string `yaml:"tools"`
+	}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse project file %s: %w", path, err)
+	}
+	tools := map[string]string{}
+	for name, vrs := range data.Tools {
+		vrs = strings.TrimSpace(vrs)
+		if vrs == "" {
+			return nil, fmt.Errorf("project file %s: empty version for %s", path, name)
+		}
+		tools[strings.ToLower(name)] = vrs
+	}
+	return &File{Path: path, Tools: tools}, nil
+}
+
+// Version returns the version pinned for the tool, if any.
+func (f *File) Version(tool string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	vrs, ok := f.Tools[tool]
+	return vrs, ok
+}
+
+// ToolNames returns the names of the pinned tools, sorted.
+func (f *File) ToolNames() []string {
+	if f == nil {
+		return nil
+	}
+	names := make([]string, 0, len(f.Tools))
+	for name := range f.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PinnedVersion returns the version of the tool pinned by the nearest project file
+// starting from the working directory, along with the project file path.
+// When the lockfile is up to date for the tool, the exact locked tag is returned instead.
+// Errors reading the project file are ignored, as pinning is best effort.
+func PinnedVersion(tool string) (string, string) {
+	f, err := FindFromWd()
+	if err != nil || f == nil {
+		return "", ""
+	}
+	vrs, ok := f.Version(tool)
+	if !ok {
+		return "", ""
+	}
+	if l, err := LoadLock(LockPath(f.Path)); err == nil {
+		if tag, ok := l.Locked(tool, vrs); ok {
+			return tag, f.Path
+		}
+	}
+	return vrs, f.Path
+}
@@ -0,0 +1,57 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind_WalksUpToNearestFile(t *testing.T) {
+	td := t.TempDir()
+	content := "tools:\n  kubectl: v1.30.4\n  Helm: 3.14\n"
+	if err := os.WriteFile(filepath.Join(td, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	nested := filepath.Join(td, "a", "b", "c")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create nested dir: %v", err)
+	}
+
+	f, err := Find(nested)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if f == nil || f.Path != filepath.Join(td, FileName) {
+		t.Fatalf("expected project file at %s, got %+v", td, f)
+	}
+	if vrs, ok := f.Version("kubectl"); !ok || vrs != "v1.30.4" {
+		t.Fatalf("unexpected kubectl version %q", vrs)
+	}
+	// versions must be kept as written and tool names lowercased
+	if vrs, ok := f.Version("helm"); !ok || vrs != "3.14" {
+		t.Fatalf("unexpected helm version %q", vrs)
+	}
+	if names := f.ToolNames(); len(names) != 2 || names[0] != "helm" {
+		t.Fatalf("unexpected tool names %v", names)
+	}
+}
+
+func TestFind_NoFile(t *testing.T) {
+	f, err := Find(t.TempDir())
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if _, ok := f.Version("kubectl"); ok {
+		t.Fatalf("expected no pinned version without a project file")
+	}
+}
+
+func TestLoad_EmptyVersion(t *testing.T) {
+	p := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(p, []byte("tools:\n  kubectl: \"\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	if _, err := Load(p); err == nil {
+		t.Fatalf("expected error for empty version")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package shim
+
+import (
+	"os"
+	"syscall"
+)
+
+// Exec replaces the current process with the given binary.
+func Exec(binary string, args []string) error {
+	return syscall.Exec(binary, args, os.Environ())
+}
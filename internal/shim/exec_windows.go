@@ -0,0 +1,25 @@
+//go:build windows
+
+package shim
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Exec runs the given binary and exits with its exit code, as windows cannot replace the current process.
+func Exec(binary string, args []string) error {
+	c := exec.Command(binary, args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
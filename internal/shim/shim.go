@@ -0,0 +1,125 @@
+package shim
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+// Mode values for the "mode" setting.
+const (
+	// ModeSymlink makes bin-path/<tool> a symlink to the global version (default).
+	ModeSymlink = "symlink"
+	// ModeShim makes bin-path/<tool> a dispatcher choosing the version at exec time.
+	ModeShim = "shim"
+)
+
+// Version sources, in order of precedence.
+const (
+	SourceEnv     = "environment"
+	SourceProject = "project"
+	SourceGlobal  = "global"
+)
+
+// ErrNoVersion is returned by Resolve when no version is selected for the tool.
+var ErrNoVersion = errors.New("no version selected")
+
+// EnvVar returns the name of the environment variable overriding the tool version,
+// e.g. VRSR_KUBECTL_VERSION.
+func EnvVar(tool string) string {
+	name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(tool))
+	return "VRSR_" + name + "_VERSION"
+}
+
+// Script returns the content of the shim dispatching the tool through vrsrPath.
+// The paths in use when the shim is written are passed on to `vrsr exec`, so the shim
+// still finds the binaries when vrsr was configured through flags. They are not
+// exported, so they do not leak into the tool and its children.
+func Script(vrsrPath, tool, binPath, vrsPath string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s: %s (do not edit, managed by vrsr)
+exec %s exec --bin-path %s --vrs-path %s %s "$@"
+`, utils.ShimMarker, tool, shellQuote(vrsrPath), shellQuote(binPath), shellQuote(vrsPath), shellQuote(tool))
+}
+
+// shellQuote quotes s as a single word for sh, no character being special within single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Install writes the shim for the tool into binPath, replacing any existing file or symlink.
+func Install(binPath, vrsPath, tool string) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("shim mode is not supported on windows")
+	}
+	vrsrPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the vrsr executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(vrsrPath); err == nil {
+		vrsrPath = resolved
+	}
+	if err := utils.EnsurePathExists(binPath); err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(binPath, "."+tool+"-shim-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+	_, err = tmpFile.WriteString(Script(vrsrPath, tool, binPath, vrsPath))
+	if err1 := tmpFile.Close(); err == nil && err1 != nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0755); err != nil {
+		return err
+	}
+	// rename replaces the previous symlink or shim in one step
+	return os.Rename(tmpFile.Name(), filepath.Join(binPath, tool))
+}
+
+// Resolve returns the version of the tool to execute and where it comes from:
+// the VRSR_<TOOL>_VERSION environment variable, then the nearest project file,
+// then the global version.
+func Resolve(binPath, tool string) (string, string, error) {
+	if vrs := strings.TrimSpace(os.Getenv(EnvVar(tool))); vrs != "" {
+		return vrs, SourceEnv, nil
+	}
+	if vrs, _ := project.PinnedVersion(tool); vrs != "" {
+		return vrs, SourceProject, nil
+	}
+	vrs, err := utils.GetVrsInUse(binPath, tool)
+	if err != nil {
+		return "", "", err
+	}
+	if vrs == "" {
+		return "", "", fmt.Errorf("%w for %s: run `vrsr %s use <version>` or set %s", ErrNoVersion, tool, tool, EnvVar(tool))
+	}
+	return vrs, SourceGlobal, nil
+}
+
+// BinaryPath returns the installed binary for the tool version, which may also be an
+// alias, a partial version or a constraint resolved against the installed versions.
+func BinaryPath(vrsPath, tool, vrs string) (string, error) {
+	if fileName, err := utils.GetBinaryPath(vrsPath, tool, vrs); err == nil {
+		if _, err := os.Stat(fileName); err == nil {
+			return fileName, nil
+		}
+	}
+	resolved, err := utils.ResolveInstalled(vrsPath, tool, vrs)
+	if err != nil {
+		return "", fmt.Errorf("%s version %s is not installed: run `vrsr %s install %s`", tool, vrs, tool, vrs)
+	}
+	return utils.GetBinaryPath(vrsPath, tool, resolved)
+}
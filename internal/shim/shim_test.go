@@ -0,0 +1,136 @@
+package shim
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stepbeta/vrsr/internal/project"
+	"github.com/stepbeta/vrsr/internal/utils"
+)
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("kube-apiserver"); got != "VRSR_KUBE_APISERVER_VERSION" {
+		t.Fatalf("unexpected env var name %s", got)
+	}
+}
+
+func TestResolve_Precedence(t *testing.T) {
+	td := t.TempDir()
+	binPath := filepath.Join(td, "bin")
+	tool := "restool"
+	t.Chdir(td)
+	t.Setenv(EnvVar(tool), "")
+
+	if _, _, err := Resolve(binPath, tool); err == nil {
+		t.Fatalf("expected error when no version is selected")
+	}
+
+	if err := utils.WriteGlobalVersion(binPath, tool, "v1.0.0"); err != nil {
+		t.Fatalf("failed to write global version: %v", err)
+	}
+	// the global version is only read through the shim
+	if err := os.WriteFile(filepath.Join(binPath, tool), []byte("#!/bin/sh\n"+utils.ShimMarker+"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write shim: %v", err)
+	}
+	if vrs, src, err := Resolve(binPath, tool); err != nil || vrs != "v1.0.0" || src != SourceGlobal {
+		t.Fatalf("expected global version, got %s from %s (%v)", vrs, src, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(td, project.FileName), []byte("tools:\n  restool: v2.0.0\n"), 0o644); err != nil {
+		t.Fatalf("failed to write project file: %v", err)
+	}
+	if vrs, src, err := Resolve(binPath, tool); err != nil || vrs != "v2.0.0" || src != SourceProject {
+		t.Fatalf("expected project version, got %s from %s (%v)", vrs, src, err)
+	}
+
+	t.Setenv(EnvVar(tool), "v3.0.0")
+	if vrs, src, err := Resolve(binPath, tool); err != nil || vrs != "v3.0.0" || src != SourceEnv {
+		t.Fatalf("expected env version, got %s from %s (%v)", vrs, src, err)
+	}
+}
+
+func TestBinaryPath_MatchesEquivalentVersions(t *testing.T) {
+	vrsPath := t.TempDir()
+	tool := "bptool"
+	if err := os.MkdirAll(filepath.Join(vrsPath, tool), 0o755); err != nil {
+		t.Fatalf("failed to create tool dir: %v", err)
+	}
+	want := filepath.Join(vrsPath, tool, tool+"-v1.2.3")
+	if err := os.WriteFile(want, []byte("x"), 0o755); err != nil {
+		t.Fatalf("failed to write binary: %v", err)
+	}
+	got, err := BinaryPath(vrsPath, tool, "1.2.3")
+	if err != nil || got != want {
+		t.Fatalf("expected %s, got %s (%v)", want, got, err)
+	}
+	if _, err := BinaryPath(vrsPath, tool, "v9.9.9"); err == nil {
+		t.Fatalf("expected error for a version not installed")
+	}
+}
+
+func TestInstall_WritesShim(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shims are not supported on windows")
+	}
+	binPath := t.TempDir()
+	// an existing symlink is replaced by the shim
+	if err := os.Symlink("/nonexistent", filepath.Join(binPath, "shtool")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := Install(binPath, "/vrs", "shtool"); err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	target := filepath.Join(binPath, "shtool")
+	if !utils.IsShim(target) {
+		t.Fatalf("expected %s to be a shim", target)
+	}
+	st, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if st.Mode().Perm()&0111 == 0 {
+		t.Fatalf("expected shim to be executable")
+	}
+}
+
+func TestScript_QuotesPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shims are not supported on windows")
+	}
+	td := t.TempDir()
+	dir := filepath.Join(td, "it's a \"$HOME\" `dir`")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	// the fake vrsr prints its arguments one per line, and whether the paths were exported
+	vrsr := filepath.Join(dir, "vrsr")
+	fake := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done\necho \"env:${VRSR_BIN_PATH}${VRSR_VRS_PATH}\"\n"
+	if err := os.WriteFile(vrsr, []byte(fake), 0o755); err != nil {
+		t.Fatalf("failed to write fake vrsr: %v", err)
+	}
+	binPath := filepath.Join(dir, "bin")
+	vrsPath := filepath.Join(dir, "vrs $(touch pwned)")
+	script := filepath.Join(td, "qtool")
+	if err := os.WriteFile(script, []byte(Script(vrsr, "qtool", binPath, vrsPath)), 0o755); err != nil {
+		t.Fatalf("failed to write shim: %v", err)
+	}
+	t.Setenv("VRSR_BIN_PATH", "")
+	t.Setenv("VRSR_VRS_PATH", "")
+	t.Chdir(td)
+
+	out, err := exec.Command(script, "--flag", "two words").Output()
+	if err != nil {
+		t.Fatalf("shim failed: %v", err)
+	}
+	want := strings.Join([]string{"exec", "--bin-path", binPath, "--vrs-path", vrsPath, "qtool", "--flag", "two words", "env:"}, "\n") + "\n"
+	if string(out) != want {
+		t.Fatalf("unexpected arguments:\n%s\nwant:\n%s", out, want)
+	}
+	if _, err := os.Stat(filepath.Join(td, "pwned")); err == nil {
+		t.Fatalf("the shim ran a command substitution from a path")
+	}
+}
@@ -2,102 +2,143 @@ package utils
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
-
-	"github.com/schollz/progressbar/v3"
 )
 
-// DownloadBinary downloads a binary from the specified URL, handling both zipped and direct binaries.
-func DownloadBinary(dlURL, tool, version, vrsPath string, zipped bool) error {
-	osAlias := strings.ToLower(runtime.GOOS)
-	archAlias := strings.ToLower(runtime.GOARCH)
+// Supported archive types for downloaded artifacts.
+const (
+	ArchiveNone  = ""
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
 
-	// 1. Append extension if zipped
-	fullURL := fmt.Sprintf(dlURL, version, osAlias, archAlias)
-	if zipped {
-		fullURL += ".tar.gz"
-	}
+// ArchiveSpec describes how the tool binary is packaged inside a downloaded artifact.
+type ArchiveSpec struct {
+	// Type is one of ArchiveNone, ArchiveTarGz or ArchiveZip.
+	Type string
+	// BinaryPath is the path of the binary inside the archive.
+	// When empty, the first entry whose base name matches the tool is used.
+	BinaryPath string
+}
 
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("warning: failed to close response body: %v\n", err)
-		}
-	}()
+// Artifact describes a tool version to download and store into the vrs path.
+type Artifact struct {
+	Tool    string
+	Version string
+	// SourceURL is where the artifact is downloaded from.
+	SourceURL string
+	Archive   ArchiveSpec
+	// Checksum is the expected SHA-256 of the artifact, verification is skipped when empty.
+	Checksum string
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
+// DownloadBinary downloads the artifact from its source URL, handling both archived and direct binaries.
+// Interrupted downloads are retried and resumed, see DownloadResumable, reporting the progress to w.
+// When ctx is done the download is abandoned, its partial file kept in the downloads cache to be
+// resumed by the next attempt.
+func DownloadBinary(ctx context.Context, w io.Writer, vrsPath string, a Artifact) error {
+	return DownloadResumable(ctx, w, a.SourceURL, a.SourceURL, func(r io.Reader) error {
+		return SaveBinary(r, vrsPath, a)
+	})
+}
 
+// SaveBinary stores the tool binary read from r as vrsPath/tool/tool-version,
+// extracting it from the archive first if needed, and records its manifest.
+// The SHA-256 of the whole downloaded artifact is computed while streaming and recorded in
+// the manifest. When a checksum is set, the binary is only moved into place if it matches.
+func SaveBinary(r io.Reader, vrsPath string, a Artifact) error {
+	tool, archive := a.Tool, a.Archive
 	finalPath := filepath.Join(vrsPath, tool)
 	if err := EnsurePathExists(finalPath); err != nil {
-		return err
-	}
-	destPath := filepath.Join(finalPath, tool+"-"+version)
-
-	// 2. Branch logic based on zipped flag
-	if zipped {
-		// Construct the expected path inside the tar: "linux-amd64/toolname"
-		internalArchivePath := fmt.Sprintf("%s-%s/%s", osAlias, archAlias, tool)
-		return extractSpecificFile(resp.Body, internalArchivePath, destPath, resp.ContentLength)
+		return fmt.Errorf("error ensuring vrs path exists: %w", err)
 	}
+	m := NewManifest(tool, a.Version)
+	m.SourceURL = a.SourceURL
+	destPath := filepath.Join(finalPath, m.Binary)
 
-	// Direct binary logic (your original code)
+	// write to temp file then move (safer)
 	tmpFile, err := os.CreateTemp(finalPath, tool+"-download-*")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer func() {
 		// Clean up if we don't rename
-		if err := os.Remove(tmpFile.Name()); err != nil {
-			fmt.Printf("warning: failed to remove temp file: %v\n", err)
-		}
+		_ = os.Remove(tmpFile.Name())
 	}()
 
-	bar := progressbar.DefaultBytes(resp.ContentLength, "Downloading...")
-	if _, err = io.Copy(io.MultiWriter(tmpFile, bar), resp.Body); err != nil {
-		return err
+	cr := newChecksumReader(r)
+	r = cr
+	switch archive.Type {
+	case ArchiveNone:
+		_, err = io.Copy(tmpFile, r)
+	case ArchiveTarGz:
+		err = extractFromTarGz(r, tmpFile, tool, archive.BinaryPath)
+	case ArchiveZip:
+		err = extractFromZip(r, tmpFile, tool, archive.BinaryPath)
+	default:
+		err = fmt.Errorf("unsupported archive type %q", archive.Type)
 	}
-	if err = tmpFile.Close(); err != nil {
-		return err
+	if err1 := tmpFile.Close(); err == nil && err1 != nil {
+		err = err1
 	}
-
-	if err = os.Rename(tmpFile.Name(), destPath); err != nil {
+	if err != nil {
+		return fmt.Errorf("failed to save download: %w", err)
+	}
+	if a.Checksum != "" {
+		if err := cr.verify(a.Checksum); err != nil {
+			return err
+		}
+	} else if err := cr.drain(); err != nil {
 		return err
 	}
+	m.SHA256 = cr.sum()
 
-	return os.Chmod(destPath, 0755)
+	if err := os.Rename(tmpFile.Name(), destPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file to destination: %w", err)
+	}
+	// make it executable
+	if err := os.Chmod(destPath, 0755); err != nil {
+		return fmt.Errorf("failed to set executable permission: %w", err)
+	}
+	if err := WriteManifest(vrsPath, m); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
 }
 
-// extractSpecificFile extracts a specific file from a gzip-compressed tar archive.
-func extractSpecificFile(gzipStream io.Reader, internalPath, destPath string, size int64) error {
-	// 1. Setup progress bar for the download stream
-	bar := progressbar.DefaultBytes(size, "Downloading & Extracting...")
+// isArchivedBinary reports whether the archive entry name is the binary we are looking for.
+func isArchivedBinary(name, tool, binaryPath string) bool {
+	name = path.Clean(filepath.ToSlash(name))
+	if binaryPath != "" {
+		return name == path.Clean(binaryPath)
+	}
+	base := path.Base(name)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(base, tool+".exe")
+	}
+	return base == tool
+}
 
-	// 2. Initialize gzip reader
-	uncompressedStream, err := gzip.NewReader(io.TeeReader(gzipStream, bar))
+// extractFromTarGz copies the tool binary out of a gzip-compressed tar archive.
+func extractFromTarGz(gzipStream io.Reader, dst io.Writer, tool, binaryPath string) error {
+	uncompressedStream, err := gzip.NewReader(gzipStream)
 	if err != nil {
 		return fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer func() {
-		if err = uncompressedStream.Close(); err != nil {
-			fmt.Printf("warning: failed to close gzip reader: %v\n", err)
-		}
+		_ = uncompressedStream.Close()
 	}()
 
 	tarReader := tar.NewReader(uncompressedStream)
-
-	// 3. Iterate through archive entries
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -106,26 +147,52 @@ func extractSpecificFile(gzipStream io.Reader, internalPath, destPath string, si
 		if err != nil {
 			return fmt.Errorf("error reading tar: %w", err)
 		}
+		if header.Typeflag != tar.TypeReg || !isArchivedBinary(header.Name, tool, binaryPath) {
+			continue
+		}
+		if _, err := io.Copy(dst, tarReader); err != nil {
+			return fmt.Errorf("failed to extract file: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("binary '%s' not found inside the archive", tool)
+}
 
-		// 4. Check if the current entry matches our dynamic path
-		// We use filepath.ToSlash to ensure cross-platform path consistency
-		if header.Name == internalPath || filepath.Clean(header.Name) == internalPath {
-			outFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-			if err != nil {
-				return fmt.Errorf("failed to create destination: %w", err)
-			}
-			defer func() {
-				if err = outFile.Close(); err != nil {
-					fmt.Printf("warning: failed to close output file: %v\n", err)
-				}
-			}()
+// extractFromZip copies the tool binary out of a zip archive.
+// Zip archives need random access, so the stream is spooled to a temp file first.
+func extractFromZip(zipStream io.Reader, dst io.Writer, tool, binaryPath string) error {
+	spool, err := os.CreateTemp("", tool+"-archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+	size, err := io.Copy(spool, zipStream)
+	if err != nil {
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
 
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return fmt.Errorf("failed to extract file: %w", err)
-			}
-			return nil
+	zipReader, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() || !isArchivedBinary(f.Name, tool, binaryPath) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open archived file: %w", err)
 		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		if _, err := io.Copy(dst, rc); err != nil {
+			return fmt.Errorf("failed to extract file: %w", err)
+		}
+		return nil
 	}
-
-	return fmt.Errorf("binary '%s' not found inside the archive", internalPath)
+	return fmt.Errorf("binary '%s' not found inside the archive", tool)
 }
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"testing"
+)
+
+// tarGz returns a gzip-compressed tar archive of the given files, in order.
+func tarGz(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f[0], Mode: 0755, Size: int64(len(f[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(f[1])); err != nil {
+			t.Fatalf("failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// incompressible returns n random bytes, which gzip cannot shrink.
+func incompressible(t *testing.T, n int) string {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("failed to generate content: %v", err)
+	}
+	return string(b)
+}
+
+func TestSaveBinary_RecordsChecksumOfWholeArchive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binary is looked up as tool.exe on windows")
+	}
+	vrsPath := t.TempDir()
+	// the binary is not the last entry, extraction stops before the end of the archive
+	archive := tarGz(t, [2]string{"sumtool", "binary"}, [2]string{"README.md", incompressible(t, 1<<18)})
+	sum := sha256.Sum256(archive)
+
+	if err := SaveBinary(bytes.NewReader(archive), vrsPath, Artifact{
+		Tool:    "sumtool",
+		Version: "v1.0.0",
+		Archive: ArchiveSpec{Type: ArchiveTarGz},
+	}); err != nil {
+		t.Fatalf("SaveBinary returned error: %v", err)
+	}
+	m, err := ReadManifest(vrsPath, "sumtool", "v1.0.0")
+	if err != nil {
+		t.Fatalf("ReadManifest returned error: %v", err)
+	}
+	if want := hex.EncodeToString(sum[:]); m.SHA256 != want {
+		t.Fatalf("expected the manifest to record the SHA-256 of the whole archive %s, got %s", want, m.SHA256)
+	}
+}
@@ -1,76 +1,440 @@
 package utils
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/google/go-github/v78/github"
 )
 
-// SaveToCache saves release data to cache file
-func SaveToCache(tool string, allReleases []*github.RepositoryRelease) {
-	releasesData, err := json.Marshal(ReleasesData{
-		Timestamp: time.Now().UTC(),
-		Releases:  allReleases,
-	})
+// CacheVersion is the version of the releases cache schema written by vrsr.
+// Caches without a version hold the full releases as returned by the GitHub API.
+const CacheVersion = 1
+
+var (
+	sourcesMu sync.RWMutex
+	// releasesSources maps tool names to the function identifying the source of their releases.
+	releasesSources = map[string]func() string{}
+)
+
+// RegisterReleasesSource sets the function identifying the source of the releases of the tool,
+// as a slash-separated path such as "github.com/kubernetes/kubernetes". The releases cache is
+// keyed by it, so tools released from the same source share it. The function is called each
+// time the cache is accessed, as the source may depend on settings loaded after registration.
+func RegisterReleasesSource(tool string, source func() string) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	releasesSources[tool] = source
+}
+
+// releasesSource returns the source of the releases of the tool, empty when unknown.
+func releasesSource(tool string) string {
+	sourcesMu.RLock()
+	source, ok := releasesSources[tool]
+	sourcesMu.RUnlock()
+	if !ok {
+		return ""
+	}
+	s := source()
+	if s == "" || !filepath.IsLocal(filepath.FromSlash(s)) {
+		return ""
+	}
+	return path.Clean(s)
+}
+
+// LockCache takes the lock of the releases cache of the tool, so that concurrent vrsr processes
+// do not fetch and write it at the same time, waiting as LockFile does. The returned function releases it.
+func LockCache(ctx context.Context, w io.Writer, tool string) (func(), error) {
+	cachePath, err := GetCachePath(tool)
 	if err != nil {
-		fmt.Println("Failed to marshal release data to json:", err)
-		return
+		return nil, err
+	}
+	return LockFile(ctx, w, cachePath+".lock")
+}
+
+// Release is the cached summary of a GitHub release. Its JSON names match the ones of
+// the GitHub API, so caches written before the schema was versioned decode as well.
+type Release struct {
+	Tag string `json:"tag_name"`
+	// Semver is the version the tag parses to, empty when it is not a semantic version.
+	Semver      string         `json:"semver,omitempty"`
+	Prerelease  bool           `json:"prerelease,omitempty"`
+	Draft       bool           `json:"draft,omitempty"`
+	PublishedAt *time.Time     `json:"published_at,omitempty"`
+	Assets      []ReleaseAsset `json:"assets,omitempty"`
+}
+
+// ReleaseAsset is the cached summary of a file attached to a GitHub release.
+type ReleaseAsset struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	// Digest is the digest published by GitHub, e.g. "sha256:<hex>".
+	Digest string `json:"digest,omitempty"`
+}
+
+// NewRelease returns the cached summary of the GitHub release.
+func NewRelease(r *github.RepositoryRelease) Release {
+	rel := Release{
+		Tag:        r.GetTagName(),
+		Prerelease: r.GetPrerelease(),
+		Draft:      r.GetDraft(),
+	}
+	if v, err := semver.NewVersion(rel.Tag); err == nil {
+		rel.Semver = v.String()
+	}
+	if r.PublishedAt != nil {
+		published := r.GetPublishedAt().UTC()
+		rel.PublishedAt = &published
+	}
+	for _, a := range r.Assets {
+		rel.Assets = append(rel.Assets, ReleaseAsset{
+			ID:     a.GetID(),
+			Name:   a.GetName(),
+			Size:   a.GetSize(),
+			Digest: a.GetDigest(),
+		})
+	}
+	return rel
+}
+
+// CompactReleases returns the cached summaries of the GitHub releases, along with
+// their release notes by tag.
+func CompactReleases(releases []*github.RepositoryRelease) ([]Release, map[string]string) {
+	compact := make([]Release, 0, len(releases))
+	notes := make(map[string]string)
+	for _, r := range releases {
+		compact = append(compact, NewRelease(r))
+		if r.GetBody() != "" {
+			notes[r.GetTagName()] = r.GetBody()
+		}
+	}
+	return compact, notes
+}
+
+// WriteCache stores release data in the cache file as is, keeping its timestamp.
+func WriteCache(tool string, data ReleasesData) error {
+	data.Version = CacheVersion
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
 	}
 	cachePath, err := GetCachePath(tool)
 	if err != nil {
-		fmt.Println("Failed to retrieve cache path:", err)
-		return
+		return err
 	}
-	err = EnsurePathExists(filepath.Dir(cachePath))
+	return WriteFileAtomic(cachePath, content, 0644)
+}
+
+// WriteFileAtomic replaces the file at path with content and the given permissions, so that readers
+// never see it half written. The content is written to a temp file readable by the current user only
+// until renamed, whatever the mode of the file it replaces.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	if err := EnsurePathExists(filepath.Dir(path)); err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
 	if err != nil {
-		fmt.Println("Failed to create cache dir:", err)
-		return
+		return err
+	}
+	_, err = tmpFile.Write(content)
+	if err1 := tmpFile.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
 	}
-	err = os.WriteFile(cachePath, []byte(releasesData), 0644)
 	if err != nil {
-		fmt.Println("Failed to save release data to cache:", err)
-		return
+		_ = os.Remove(tmpFile.Name())
 	}
+	return err
 }
 
-// ReadFromCache reads cached release data from file
+// ReadFromCache reads cached release data from file, the newest limit releases when limit is set.
+// The cache may only hold the newest releases, see ReleasesData.Satisfies.
+// Caches in an older schema are migrated, the ones written by a newer vrsr are ignored.
 func ReadFromCache(tool string, limit int) (ReleasesData, error) {
-	var err error
 	cachePath, err := GetCachePath(tool)
 	if err != nil {
-		fmt.Println("Failed to retrieve cache path:", err)
 		return ReleasesData{}, err
 	}
 	content, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		content, err = adoptLegacyCache(tool, cachePath)
+	}
 	if os.IsNotExist(err) {
 		// cache file not found
 		return ReleasesData{}, nil
 	}
 	if err != nil {
-		fmt.Println("Failed to read cache data from file:", err)
-		return ReleasesData{}, err
+		return ReleasesData{}, fmt.Errorf("failed to read the releases cache: %w", err)
 	}
 	var cacheData ReleasesData
-	err = json.Unmarshal(content, &cacheData)
+	if err := json.Unmarshal(content, &cacheData); err != nil {
+		return ReleasesData{}, fmt.Errorf("failed to decode the releases cache %s: %w", cachePath, err)
+	}
+	switch {
+	case cacheData.Version > CacheVersion:
+		// unknown schema, treat it as missing so that it is fetched again
+		return ReleasesData{}, nil
+	case cacheData.Version < CacheVersion:
+		cacheData, err = migrateCache(tool, cachePath, content)
+		if err != nil {
+			return ReleasesData{}, fmt.Errorf("failed to migrate the releases cache %s: %w", cachePath, err)
+		}
+	}
+	// apply limit if found
+	return cacheData.First(limit), nil
+}
+
+// adoptLegacyCache returns the content of the releases cache kept under the tool name, from before
+// caches were keyed by source, and moves it to cachePath along with its release notes.
+// It is only moved when no other process holds the lock of the cache, moving it is best effort.
+func adoptLegacyCache(tool, cachePath string) ([]byte, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	legacy := legacyCachePath(homeDir, tool)
+	if legacy == cachePath {
+		return nil, os.ErrNotExist
+	}
+	content, err := os.ReadFile(legacy)
+	if os.IsNotExist(err) {
+		// moved meanwhile by another process
+		return os.ReadFile(cachePath)
+	}
 	if err != nil {
-		fmt.Println("Failed to unmarshal cache data:", err)
+		return nil, err
+	}
+	unlock, ok, err := tryLock(cachePath + ".lock")
+	if err != nil || !ok {
+		return content, nil
+	}
+	defer unlock()
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		// written meanwhile by another process, the legacy cache is stale
+		return content, nil
+	}
+	if err := os.Rename(legacy, cachePath); err != nil {
+		return content, nil
+	}
+	if _, err := os.Stat(releaseNotesPath(legacy)); err == nil {
+		_ = os.Rename(releaseNotesPath(legacy), releaseNotesPath(cachePath))
+	}
+	return content, nil
+}
+
+// migrateCache converts content, a cache holding the full GitHub releases, to the current schema.
+// The cache at cachePath is rewritten with it and its release notes moved to their own file, unless
+// another process holds the lock of the cache or changed it meanwhile. Rewriting it is best effort,
+// the cache is converted again on the next read otherwise.
+func migrateCache(tool, cachePath string, content []byte) (ReleasesData, error) {
+	var legacy struct {
+		Timestamp    time.Time                   `json:"timestamp"`
+		Releases     []*github.RepositoryRelease `json:"releases"`
+		ETag         string                      `json:"etag,omitempty"`
+		LastModified string                      `json:"last_modified,omitempty"`
+	}
+	if err := json.Unmarshal(content, &legacy); err != nil {
 		return ReleasesData{}, err
 	}
-	// apply limit if found
-	if limit > 0 && len(cacheData.Releases) > limit {
-		return ReleasesData{
-			Timestamp: cacheData.Timestamp,
-			Releases:  cacheData.Releases[0:limit],
-		}, nil
+	releases, notes := CompactReleases(legacy.Releases)
+	data := ReleasesData{
+		Version:      CacheVersion,
+		Timestamp:    legacy.Timestamp,
+		Releases:     releases,
+		ETag:         legacy.ETag,
+		LastModified: legacy.LastModified,
+	}
+	unlock, ok, err := tryLock(cachePath + ".lock")
+	if err != nil || !ok {
+		return data, nil
+	}
+	defer unlock()
+	if current, err := os.ReadFile(cachePath); err != nil || !bytes.Equal(current, content) {
+		return data, nil
 	}
-	return cacheData, nil
+	if err := SaveReleaseNotes(tool, notes); err == nil {
+		_ = WriteCache(tool, data)
+	}
+	return data, nil
 }
 
 type ReleasesData struct {
-	Timestamp time.Time                   `json:"timestamp"`
-	Releases  []*github.RepositoryRelease `json:"releases"`
+	// Version is the schema version of the cache, see CacheVersion.
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	Releases  []Release `json:"releases"`
+	// Complete is set when Releases go down to the oldest release.
+	// Otherwise they are only the newest releases, down to the last one listed.
+	Complete bool `json:"complete,omitempty"`
+	// ETag and LastModified validate the first page of the releases list, for conditional requests.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Satisfies reports whether the data holds the limit newest releases, or all of them when limit is 0.
+func (d ReleasesData) Satisfies(limit int) bool {
+	return d.Complete || (limit > 0 && len(d.Releases) >= limit)
+}
+
+// First returns the data with only the limit newest releases, or all of them when limit is 0.
+func (d ReleasesData) First(limit int) ReleasesData {
+	if limit > 0 && len(d.Releases) > limit {
+		d.Releases = d.Releases[:limit]
+		d.Complete = false
+	}
+	return d
+}
+
+// getReleaseNotesPath returns the path of the file holding the release notes of the tool.
+func getReleaseNotesPath(tool string) (string, error) {
+	cachePath, err := GetCachePath(tool)
+	if err != nil {
+		return "", err
+	}
+	return releaseNotesPath(cachePath), nil
+}
+
+// releaseNotesPath returns the path of the release notes stored along with the releases cache at cachePath.
+func releaseNotesPath(cachePath string) string {
+	return strings.TrimSuffix(cachePath, "releases.json") + "release-notes.json"
+}
+
+// readReleaseNotes returns the release notes of the tool by tag.
+func readReleaseNotes(tool string) (map[string]string, error) {
+	notesPath, err := getReleaseNotesPath(tool)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(notesPath)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	notes := map[string]string{}
+	if err := json.Unmarshal(content, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// SaveReleaseNotes adds the release notes, by tag, to the ones stored for the tool.
+// They are kept apart from the releases cache, which is read far more often.
+func SaveReleaseNotes(tool string, notes map[string]string) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	stored, err := readReleaseNotes(tool)
+	if err != nil {
+		// unreadable notes are replaced
+		stored = map[string]string{}
+	}
+	maps.Copy(stored, notes)
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	notesPath, err := getReleaseNotesPath(tool)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(notesPath, content, 0644)
+}
+
+// ReadReleaseNotes returns the stored release notes of the tool version, ok is false when there are none.
+func ReadReleaseNotes(tool, tag string) (string, bool, error) {
+	notes, err := readReleaseNotes(tool)
+	if err != nil {
+		return "", false, err
+	}
+	body, ok := notes[tag]
+	return body, ok, nil
+}
+
+// PartialReleases is the progress of a fetch of the releases interrupted midway, e.g. by the rate limit.
+type PartialReleases struct {
+	// Timestamp is when the interrupted fetch started.
+	Timestamp time.Time `json:"timestamp"`
+	// Repo is the "org/repo" the releases come from.
+	Repo string `json:"repo"`
+	// NextPage is the first page not fetched yet, fetched PerPage releases at a time.
+	NextPage int       `json:"next_page"`
+	PerPage  int       `json:"per_page"`
+	Releases []Release `json:"releases"`
+}
+
+// getPartialCachePath returns the path of the progress of an interrupted fetch of the releases.
+func getPartialCachePath(tool string) (string, error) {
+	cachePath, err := GetCachePath(tool)
+	if err != nil {
+		return "", err
+	}
+	return cachePath + ".partial", nil
+}
+
+// SavePartialCache stores the progress of an interrupted fetch of the releases of the tool.
+func SavePartialCache(tool string, p PartialReleases) error {
+	content, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	partialPath, err := getPartialCachePath(tool)
+	if err != nil {
+		return err
+	}
+	if err := EnsurePathExists(filepath.Dir(partialPath)); err != nil {
+		return err
+	}
+	return os.WriteFile(partialPath, content, 0644)
+}
+
+// ReadPartialCache returns the progress of an interrupted fetch of the releases of the tool,
+// ok is false when there is none.
+func ReadPartialCache(tool string) (PartialReleases, bool, error) {
+	var p PartialReleases
+	partialPath, err := getPartialCachePath(tool)
+	if err != nil {
+		return p, false, err
+	}
+	content, err := os.ReadFile(partialPath)
+	if os.IsNotExist(err) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, err
+	}
+	if err := json.Unmarshal(content, &p); err != nil {
+		return p, false, err
+	}
+	return p, true, nil
+}
+
+// RemovePartialCache deletes the progress of an interrupted fetch of the releases of the tool.
+func RemovePartialCache(tool string) error {
+	partialPath, err := getPartialCachePath(tool)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFromCache_MigratesOnlyUnderLock(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("HOME", td)
+	tool := "locktool"
+	RegisterReleasesSource(tool, func() string { return "github.com/o/locktool" })
+	cachePath, err := GetCachePath(tool)
+	if err != nil {
+		t.Fatalf("failed to get cache path: %v", err)
+	}
+	// a cache from before the schema was versioned, kept under the tool name
+	legacy := []byte(`{"timestamp":"2025-01-02T03:04:05Z","releases":[{"tag_name":"v1.0.0","body":"notes"}]}`)
+	legacyPath := legacyCachePath(td, tool)
+	if err := EnsurePathExists(filepath.Dir(legacyPath)); err != nil {
+		t.Fatalf("failed to create cache dir: %v", err)
+	}
+	if err := os.WriteFile(legacyPath, legacy, 0o644); err != nil {
+		t.Fatalf("failed to write legacy cache: %v", err)
+	}
+
+	// another process writing the cache: the read is served without touching the files
+	unlock, err := LockCache(context.Background(), &bytes.Buffer{}, tool)
+	if err != nil {
+		t.Fatalf("LockCache returned error: %v", err)
+	}
+	data, err := ReadFromCache(tool, 0)
+	if err != nil || data.Version != CacheVersion || len(data.Releases) != 1 || data.Releases[0].Tag != "v1.0.0" {
+		t.Fatalf("unexpected cache %+v (%v)", data, err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Fatalf("expected the legacy cache not to be moved while locked, got %v", err)
+	}
+	unlock()
+
+	// moved on the next read, then migrated on the following one
+	for range 2 {
+		if _, err := ReadFromCache(tool, 0); err != nil {
+			t.Fatalf("ReadFromCache returned error: %v", err)
+		}
+	}
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("failed to read cache: %v", err)
+	}
+	if bytes.Contains(content, []byte("notes")) || !bytes.Contains(content, []byte(`"version":1`)) {
+		t.Fatalf("expected the cache to be migrated, got %s", content)
+	}
+	if body, ok, err := ReadReleaseNotes(tool, "v1.0.0"); err != nil || !ok || body != "notes" {
+		t.Fatalf("expected the release notes to be moved, got %q, %v, %v", body, ok, err)
+	}
+}
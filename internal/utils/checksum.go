@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrChecksumMismatch is returned when a download does not match its published checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrChecksumNotFound is returned when no checksum is published for a download.
+	ErrChecksumNotFound = errors.New("checksum not found")
+)
+
+// ParseChecksum extracts the SHA-256 of fileName from the content of a checksum file.
+// Both bare hashes ("<hash>") and sha256sum-style lines ("<hash>  <file>") are supported.
+func ParseChecksum(content []byte, fileName string) (string, error) {
+	var single string
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		lines++
+		sum := strings.ToLower(fields[0])
+		if !isSHA256(sum) {
+			continue
+		}
+		if len(fields) == 1 {
+			single = sum
+			continue
+		}
+		// sha256sum marks binary mode with a leading '*'
+		name := strings.TrimPrefix(fields[len(fields)-1], "*")
+		if name == fileName || path.Base(name) == fileName {
+			return sum, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	// a checksum file for a single artifact does not always name it (e.g. kubectl.sha256)
+	if lines == 1 && single != "" {
+		return single, nil
+	}
+	return "", fmt.Errorf("%w for %s", ErrChecksumNotFound, fileName)
+}
+
+// FetchChecksum downloads the checksum file at url and returns the SHA-256 of fileName.
+// ErrChecksumNotFound is returned when the checksum file does not exist.
+func FetchChecksum(ctx context.Context, url, fileName string) (string, error) {
+	if err := CheckOnline("fetch checksum " + url); err != nil {
+		return "", err
+	}
+	resp, err := Get(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w at %s", ErrChecksumNotFound, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksum: bad status: %s", resp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum: %w", err)
+	}
+	return ParseChecksum(content, fileName)
+}
+
+// NormalizeDigest turns a digest such as "sha256:<hash>" into a bare lowercase hash.
+// An empty string is returned for digests that are not SHA-256.
+func NormalizeDigest(digest string) string {
+	algo, sum, found := strings.Cut(digest, ":")
+	if !found {
+		sum = algo
+	} else if !strings.EqualFold(algo, "sha256") {
+		return ""
+	}
+	sum = strings.ToLower(sum)
+	if !isSHA256(sum) {
+		return ""
+	}
+	return sum
+}
+
+// isSHA256 reports whether s looks like a hex-encoded SHA-256 hash.
+func isSHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// checksumReader hashes everything read through it.
+type checksumReader struct {
+	r io.Reader
+	h hash.Hash
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	h := sha256.New()
+	return &checksumReader{r: io.TeeReader(r, h), h: h}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// sum returns the hash of what was read so far.
+func (c *checksumReader) sum() string {
+	return hex.EncodeToString(c.h.Sum(nil))
+}
+
+// drain reads the rest of the stream, which extracting a binary may leave unread,
+// so that sum covers all of it.
+func (c *checksumReader) drain() error {
+	if _, err := io.Copy(io.Discard, c.r); err != nil {
+		return fmt.Errorf("failed to read download: %w", err)
+	}
+	return nil
+}
+
+// verify drains the rest of the stream and compares its hash with the expected one.
+func (c *checksumReader) verify(expected string) error {
+	if err := c.drain(); err != nil {
+		return err
+	}
+	actual := c.sum()
+	if actual != strings.ToLower(expected) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
+
+// HashReader returns the SHA-256 of everything read from r.
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read download: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashURL downloads the artifact at url and returns its SHA-256.
+func HashURL(ctx context.Context, url string) (string, error) {
+	if err := CheckOnline("download " + url); err != nil {
+		return "", err
+	}
+	resp, err := Get(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download file: bad status: %s", resp.Status)
+	}
+	return HashReader(resp.Body)
+}
@@ -0,0 +1,321 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultDownloadRetries is how many times a failed download is retried, unless "download.retries" is set.
+	defaultDownloadRetries = 4
+	// maxRetryBackoff is the longest wait between two attempts.
+	maxRetryBackoff = 30 * time.Second
+	// partialDownloadMaxAge is how long a partial download is kept in the downloads cache to be resumed.
+	partialDownloadMaxAge = 7 * 24 * time.Hour
+)
+
+// retryBackoff is the wait before the first retry, doubled on each following one up to maxRetryBackoff.
+// Replaced in tests.
+var retryBackoff = time.Second
+
+// errStaleDownload is returned when the partial download cannot be resumed and must start over.
+var errStaleDownload = errors.New("the partial download no longer matches the remote file")
+
+// StatusError is returned for an unexpected HTTP response status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "bad status: " + e.Status
+}
+
+// IsTransient reports whether err is a failure worth retrying: network errors, truncated
+// responses, request timeouts, rate limits and server errors. Cancellations are not.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, errStaleDownload) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// Retry runs call until it succeeds, retrying transient failures (see IsTransient) up to the
+// "download.retries" setting times, waiting exponentially longer between attempts.
+// Each retry is reported to w.
+func Retry(ctx context.Context, w io.Writer, what string, call func() error) error {
+	retries := defaultDownloadRetries
+	if viper.IsSet("download.retries") {
+		retries = viper.GetInt("download.retries")
+	}
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= retries || !IsTransient(err) || ctx.Err() != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "\n%s failed (%v), retrying in %s (%d/%d)...\n", what, err, backoff, attempt+1, retries)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return context.Cause(ctx)
+		case <-t.C:
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// DownloadResumable downloads the file at url into the downloads cache and passes it to save once complete.
+// A partial download of key (e.g. the URL without its expiring signature) left by an earlier attempt is resumed
+// with a Range request when the server supports it, provided the remote file did not change since: its ETag
+// or Last-Modified date is checked, and a changed file is downloaded from scratch. Transient failures are
+// retried (see Retry) from where they left off. The progress and the retries are reported to w.
+// Concurrent downloads of the same key take turns, and the file leaves the cache once passed to save.
+func DownloadResumable(ctx context.Context, w io.Writer, key, url string, save func(r io.Reader) error) error {
+	if err := CheckOnline("download " + url); err != nil {
+		return err
+	}
+	dir, err := GetDownloadsPath()
+	if err != nil {
+		return err
+	}
+	if err := EnsurePathExists(dir); err != nil {
+		return fmt.Errorf("error ensuring downloads path exists: %w", err)
+	}
+	pruneDownloads(dir, time.Now())
+	sum := sha256.Sum256([]byte(key))
+	partPath := filepath.Join(dir, hex.EncodeToString(sum[:16])+".part")
+	unlock, err := LockFile(ctx, w, partPath+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := Retry(ctx, w, "Download", func() error {
+		return resumeDownload(ctx, w, url, partPath)
+	}); err != nil {
+		return err
+	}
+
+	f, err := os.Open(partPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+		removeDownload(partPath)
+	}()
+	return save(f)
+}
+
+// partialDownload describes the remote file a partial download comes from.
+type partialDownload struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Size is the size of the complete file, -1 when unknown.
+	Size int64 `json:"size"`
+}
+
+// validator returns the If-Range value telling whether the remote file is still the one
+// partially downloaded, empty when there is none (weak ETags do not qualify).
+func (p partialDownload) validator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// resumeDownload downloads the file at url into partPath, resuming the partial download there if any.
+// The progress is reported to w.
+func resumeDownload(ctx context.Context, w io.Writer, url, partPath string) error {
+	metaPath := partPath + ".json"
+	var meta partialDownload
+	var offset int64
+	if content, err := os.ReadFile(metaPath); err == nil && json.Unmarshal(content, &meta) == nil && meta.validator() != "" {
+		if fi, err := os.Stat(partPath); err == nil {
+			offset = fi.Size()
+		}
+	}
+	if offset > 0 && offset == meta.Size {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
+	}
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if offset == 0 || !ok || start != offset {
+			removeDownload(partPath)
+			return errStaleDownload
+		}
+		meta.Size = size
+	case http.StatusOK:
+		// the server does not support ranges, or the file changed: start over
+		offset = 0
+		flags |= os.O_TRUNC
+		meta = partialDownload{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         resp.ContentLength,
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		removeDownload(partPath)
+		return errStaleDownload
+	default:
+		if !IsTransient(&StatusError{StatusCode: resp.StatusCode}) {
+			removeDownload(partPath)
+		}
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(metaPath, content, 0644); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	bar := newBytesBar(w, meta.Size, "Downloading...")
+	if offset > 0 {
+		bar.Describe("Resuming download...")
+		_ = bar.Set64(offset)
+	}
+	_, err = io.Copy(io.MultiWriter(f, bar), resp.Body)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("download interrupted: %w", err)
+	}
+	return nil
+}
+
+// parseContentRange returns the first byte and the complete size (-1 when unknown)
+// of a "bytes <first>-<last>/<size>" Content-Range header.
+func parseContentRange(h string) (int64, int64, bool) {
+	r, ok := strings.CutPrefix(h, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	r, total, ok := strings.Cut(r, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	first, _, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size := int64(-1)
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// newBytesBar returns a progress bar of the bytes downloaded, as progressbar.DefaultBytes
+// but written to w.
+func newBytesBar(w io.Writer, size int64, description string) *progressbar.ProgressBar {
+	return progressbar.NewOptions64(size,
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowTotalBytes(true),
+		progressbar.OptionSetWidth(10),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionShowCount(),
+		progressbar.OptionOnCompletion(func() {
+			_, _ = fmt.Fprintln(w)
+		}),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+}
+
+// removeDownload deletes the partial download at partPath along with its description.
+func removeDownload(partPath string) {
+	_ = os.Remove(partPath)
+	_ = os.Remove(partPath + ".json")
+}
+
+// pruneDownloads deletes the partial downloads abandoned for longer than partialDownloadMaxAge.
+// Their locks go along, once the partial download they guard is gone.
+func pruneDownloads(dir string, now time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var locks []string
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil || now.Sub(fi.ModTime()) <= partialDownloadMaxAge {
+			continue
+		}
+		if strings.HasSuffix(e.Name(), ".lock") {
+			locks = append(locks, e.Name())
+			continue
+		}
+		_ = os.Remove(filepath.Join(dir, e.Name()))
+	}
+	for _, name := range locks {
+		partPath := filepath.Join(dir, strings.TrimSuffix(name, ".lock"))
+		if _, err := os.Stat(partPath); os.IsNotExist(err) {
+			_ = os.Remove(partPath + ".lock")
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// dropConnection answers with the headers of content and half of it, then drops the connection.
+func dropConnection(t *testing.T, w http.ResponseWriter, content, etag string) {
+	t.Helper()
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Errorf("failed to hijack the connection: %v", err)
+		return
+	}
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nETag: %s\r\n\r\n%s", len(content), etag, content[:len(content)/2])
+	_ = buf.Flush()
+	_ = conn.Close()
+}
+
+// downloadForTest downloads url with DownloadResumable and returns what was saved and reported.
+func downloadForTest(t *testing.T, url string) (string, string, error) {
+	t.Helper()
+	var saved string
+	var report bytes.Buffer
+	err := DownloadResumable(t.Context(), &report, url, url, func(r io.Reader) error {
+		b, err := io.ReadAll(r)
+		saved = string(b)
+		return err
+	})
+	return saved, report.String(), err
+}
+
+func TestDownloadResumable_ResumesDroppedDownload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	origBackoff := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = origBackoff }()
+	content := strings.Repeat("0123456789", 100)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			dropConnection(t, w, content, `"v1"`)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	saved, report, err := downloadForTest(t, srv.URL+"/tool")
+	if err != nil {
+		t.Fatalf("DownloadResumable returned error: %v", err)
+	}
+	if saved != content {
+		t.Fatalf("expected the complete file to be saved, got %d bytes", len(saved))
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=500-" {
+		t.Fatalf("expected the download to resume from byte 500, got ranges %q", ranges)
+	}
+	if !strings.Contains(report, "retrying") {
+		t.Fatalf("expected the retry to be reported, got %q", report)
+	}
+	dir, _ := GetDownloadsPath()
+	if partial, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(partial) != 0 {
+		t.Fatalf("expected the saved download to leave the cache, found %v", partial)
+	}
+}
+
+func TestDownloadResumable_StartsOverWithoutRangeSupport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	origBackoff := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = origBackoff }()
+	content, etag := strings.Repeat("a", 1000), `"v1"`
+	var ranges []string
+	ignoreRange := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			dropConnection(t, w, content, etag)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if ignoreRange {
+			// the whole file, as servers without range support answer
+			_, _ = w.Write([]byte(content))
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		change func()
+	}{
+		{"range ignored", func() { ignoreRange = true }},
+		{"file changed", func() { content, etag = strings.Repeat("b", 800), `"v2"` }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			content, etag, ignoreRange = strings.Repeat("a", 1000), `"v1"`, false
+			// keep the partial download for the next attempt
+			viper.Set("download.retries", 0)
+			defer viper.Set("download.retries", nil)
+			if _, _, err := downloadForTest(t, srv.URL+"/"+tt.name); err == nil {
+				t.Fatalf("expected the dropped download to fail")
+			}
+			tt.change()
+			saved, _, err := downloadForTest(t, srv.URL+"/"+tt.name)
+			if err != nil {
+				t.Fatalf("DownloadResumable returned error: %v", err)
+			}
+			if len(ranges) != 2 || ranges[1] != "bytes=500-" {
+				t.Fatalf("expected a resume attempt, got ranges %q", ranges)
+			}
+			if saved != content {
+				t.Fatalf("expected the whole file to be downloaded again, got %d bytes", len(saved))
+			}
+		})
+	}
+}
+
+func TestPruneDownloads(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-2 * partialDownloadMaxAge)
+	files := map[string]time.Time{
+		"stale.part": old, "stale.part.json": old, "stale.part.lock": old,
+		"fresh.part": now, "fresh.part.json": now, "fresh.part.lock": old,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("failed to age %s: %v", name, err)
+		}
+	}
+	pruneDownloads(dir, now)
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept := err == nil; kept != strings.HasPrefix(name, "fresh") {
+			t.Fatalf("unexpected pruning of %s (kept: %v)", name, kept)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultConnectTimeout is how long connecting to a server may take, unless "connect-timeout" is set.
+	DefaultConnectTimeout = 30 * time.Second
+	// DefaultIdleTimeout is how long a connection may go without receiving anything, unless "idle-timeout" is set.
+	DefaultIdleTimeout = time.Minute
+)
+
+// timeoutSetting returns the duration of the setting, or def when it is not set.
+func timeoutSetting(key string, def time.Duration) time.Duration {
+	if d := viper.GetDuration(key); d > 0 {
+		return d
+	}
+	return def
+}
+
+// idleConn is a connection failing reads once nothing was received for timeout,
+// so that a stalled download errors out instead of hanging forever.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	n, err := c.Conn.Read(b)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = fmt.Errorf("nothing received for %s (see --idle-timeout): %w", c.timeout, err)
+	}
+	return n, err
+}
+
+// Transport returns the transport shared by the requests of vrsr. It gives up connecting after the
+// "connect-timeout" setting and on connections idle for longer than the "idle-timeout" setting.
+func Transport() http.RoundTripper {
+	return sharedTransport()
+}
+
+// sharedTransport builds the transport on first use, once the settings are loaded.
+var sharedTransport = sync.OnceValue(newTransport)
+
+// newTransport returns a transport applying the connect and idle timeouts.
+func newTransport() http.RoundTripper {
+	connectTimeout := timeoutSetting("connect-timeout", DefaultConnectTimeout)
+	idleTimeout := timeoutSetting("idle-timeout", DefaultIdleTimeout)
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &idleConn{Conn: conn, timeout: idleTimeout}, nil
+	}
+	t.TLSHandshakeTimeout = connectTimeout
+	return t
+}
+
+// HTTPClient returns the client of the requests of vrsr, see Transport.
+func HTTPClient() *http.Client {
+	return &http.Client{Transport: Transport()}
+}
+
+// Get requests url, giving up when ctx is done.
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return HTTPClient().Do(req)
+}
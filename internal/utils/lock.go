@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockPollInterval is the first wait before trying again a lock held by another process.
+	lockPollInterval = 10 * time.Millisecond
+	// maxLockPollInterval is the longest wait between two attempts at a lock.
+	maxLockPollInterval = 500 * time.Millisecond
+)
+
+// LockFile takes an exclusive advisory lock on the file at path, creating it when missing.
+// While another process holds it, LockFile says so to w and tries again, waiting longer each
+// time, until the lock is released or ctx is done. The returned function releases the lock.
+func LockFile(ctx context.Context, w io.Writer, path string) (func(), error) {
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	backoff := lockPollInterval
+	for attempt := 0; ; attempt++ {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if ok {
+			return releaseLock(f), nil
+		}
+		if attempt == 0 {
+			_, _ = fmt.Fprintf(w, "Waiting for another vrsr process to release %s...\n", path)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			_ = f.Close()
+			return nil, fmt.Errorf("gave up waiting for %s: %w", path, context.Cause(ctx))
+		case <-t.C:
+		}
+		backoff = min(2*backoff, maxLockPollInterval)
+	}
+}
+
+// tryLock takes the lock on the file at path unless another process holds it, ok is false then.
+func tryLock(path string) (unlock func(), ok bool, err error) {
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok, err = tryLockFile(f); err != nil || !ok {
+		_ = f.Close()
+		return nil, false, err
+	}
+	return releaseLock(f), true, nil
+}
+
+// openLockFile opens the lock file at path, creating it and its folder when missing.
+func openLockFile(path string) (*os.File, error) {
+	if err := EnsurePathExists(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+}
+
+// releaseLock returns the function releasing the lock taken on f.
+func releaseLock(f *os.File) func() {
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}
+}
+
+// LockInstall takes the lock of the versions of the tool installed into vrsPath, so that concurrent
+// vrsr processes install them one at a time, waiting as LockFile does. The returned function releases it.
+func LockInstall(ctx context.Context, w io.Writer, vrsPath, tool string) (func(), error) {
+	return LockFile(ctx, w, filepath.Join(vrsPath, tool, ".lock"))
+}
+
+// LockInUse takes the lock of the version of the tool in use from binPath, so that concurrent
+// vrsr processes switch it one at a time, waiting as LockFile does. The returned function releases it.
+func LockInUse(ctx context.Context, w io.Writer, binPath, tool string) (func(), error) {
+	return LockFile(ctx, w, filepath.Join(binPath, ".locks", tool))
+}
+
+// SymlinkAtomic makes link a symlink to target, replacing whatever link was by renaming
+// a new symlink over it, so that there is never a moment without a link.
+func SymlinkAtomic(target, link string) error {
+	dir, name := filepath.Split(link)
+	for i := 0; ; i++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".%s-link-%d-%d", name, os.Getpid(), i))
+		err := os.Symlink(target, tmp)
+		if errors.Is(err, os.ErrExist) && i < 100 {
+			// left over by a crashed process
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, link); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockFile_WaitsUntilContextIsDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "tool")
+	unlock, err := LockFile(context.Background(), &bytes.Buffer{}, path)
+	if err != nil {
+		t.Fatalf("LockFile returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := LockFile(ctx, &out, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "Waiting for another vrsr process") {
+		t.Fatalf("expected the wait to be reported, got %q", out.String())
+	}
+
+	// the lock is taken as soon as it is released
+	time.AfterFunc(50*time.Millisecond, unlock)
+	unlock2, err := LockFile(context.Background(), &bytes.Buffer{}, path)
+	if err != nil {
+		t.Fatalf("LockFile returned error: %v", err)
+	}
+	unlock2()
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// manifestsDir is the folder, inside vrs-path/<tool>, holding the manifests of the installed versions.
+const manifestsDir = ".manifests"
+
+// Manifest records how an installed tool version got into the vrs path.
+type Manifest struct {
+	Tool string `json:"tool"`
+	// Tag is the version as originally requested/released (e.g. "v1.31.0-rc.1").
+	Tag string `json:"tag"`
+	// Version is the normalized semver of the tag, empty if it is not a valid semver.
+	Version string `json:"version,omitempty"`
+	// Binary is the file name of the binary inside vrs-path/<tool>.
+	Binary    string `json:"binary"`
+	SourceURL string `json:"source_url,omitempty"`
+	// SHA256 is the checksum of the downloaded artifact.
+	SHA256      string    `json:"sha256,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	// Migrated is set for binaries installed before manifests existed.
+	Migrated bool `json:"migrated,omitempty"`
+}
+
+// NewManifest returns the manifest of a tool version installed now for the current OS/ARCH.
+func NewManifest(tool, tag string) Manifest {
+	m := Manifest{
+		Tool:        tool,
+		Tag:         tag,
+		Binary:      tool + "-" + tag,
+		InstalledAt: time.Now().UTC(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+	}
+	if v, err := semver.NewVersion(tag); err == nil {
+		m.Version = v.String()
+	}
+	return m
+}
+
+// getManifestPath returns the path of the manifest of the tool version.
+func getManifestPath(vrsPath, tool, tag string) string {
+	return filepath.Join(vrsPath, tool, manifestsDir, tag+".json")
+}
+
+// WriteManifest stores the manifest of an installed version.
+func WriteManifest(vrsPath string, m Manifest) error {
+	p := getManifestPath(vrsPath, m.Tool, m.Tag)
+	if err := EnsurePathExists(filepath.Dir(p)); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, content, 0644)
+}
+
+// ReadManifest returns the manifest of the tool version. A binary installed before manifests
+// existed gets one built on the fly, see AdoptInstalled.
+func ReadManifest(vrsPath, tool, tag string) (Manifest, error) {
+	var m Manifest
+	content, err := os.ReadFile(getManifestPath(vrsPath, tool, tag))
+	if errors.Is(err, os.ErrNotExist) {
+		if legacy, ok := legacyManifest(vrsPath, tool, tag); ok {
+			return legacy, nil
+		}
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest for %s %s: %w", tool, tag, err)
+	}
+	return m, nil
+}
+
+// RemoveManifest deletes the manifest of the tool version.
+func RemoveManifest(vrsPath, tool, tag string) error {
+	err := os.Remove(getManifestPath(vrsPath, tool, tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// ReadManifests returns the manifests of the installed versions of the tool whose binary
+// is still present. Binaries installed before manifests existed get one built on the fly,
+// nothing is written: see AdoptInstalled.
+func ReadManifests(vrsPath, tool string) ([]Manifest, error) {
+	toolDir := filepath.Join(vrsPath, tool)
+	files, err := os.ReadDir(filepath.Join(toolDir, manifestsDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	manifests := make([]Manifest, 0, len(files))
+	for _, f := range files {
+		tag, ok := strings.CutSuffix(f.Name(), ".json")
+		if f.IsDir() || !ok {
+			continue
+		}
+		m, err := ReadManifest(vrsPath, tool, tag)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(toolDir, m.Binary)); err != nil {
+			// binary removed by hand
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	legacy, err := legacyManifests(vrsPath, tool)
+	if err != nil {
+		return nil, err
+	}
+	return append(manifests, legacy...), nil
+}
+
+// AdoptInstalled writes the manifest of every binary of the tool installed into vrsPath before
+// manifests existed. The caller holds the install lock of the tool (see LockInstall), so that
+// an install does not get its manifest replaced.
+func AdoptInstalled(vrsPath, tool string) error {
+	legacy, err := legacyManifests(vrsPath, tool)
+	if err != nil {
+		return err
+	}
+	for _, m := range legacy {
+		if err := WriteManifest(vrsPath, m); err != nil {
+			return fmt.Errorf("failed to adopt %s: %w", m.Binary, err)
+		}
+	}
+	return nil
+}
+
+// legacyManifests returns the manifests of the "<tool>-<version>" binaries in vrsPath lacking one.
+func legacyManifests(vrsPath, tool string) ([]Manifest, error) {
+	files, err := os.ReadDir(filepath.Join(vrsPath, tool))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifests []Manifest
+	for _, f := range files {
+		tag, ok := strings.CutPrefix(f.Name(), tool+"-")
+		if f.IsDir() || !ok {
+			continue
+		}
+		if _, err := os.Stat(getManifestPath(vrsPath, tool, tag)); err == nil {
+			continue
+		}
+		if m, ok := legacyManifest(vrsPath, tool, tag); ok {
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests, nil
+}
+
+// legacyManifest returns the manifest of the "<tool>-<tag>" binary installed before manifests existed,
+// ok is false when there is no such binary.
+func legacyManifest(vrsPath, tool, tag string) (Manifest, bool) {
+	if _, err := semver.NewVersion(tag); err != nil {
+		// not a version (e.g. a temp download file)
+		return Manifest{}, false
+	}
+	m := NewManifest(tool, tag)
+	fi, err := os.Stat(filepath.Join(vrsPath, tool, m.Binary))
+	if err != nil || !fi.Mode().IsRegular() {
+		return Manifest{}, false
+	}
+	m.Migrated = true
+	m.InstalledAt = fi.ModTime().UTC()
+	return m, true
+}
+
+// findManifest returns the manifest of the tool version installed as the given binary.
+func findManifest(vrsPath, tool, binary string) (Manifest, bool) {
+	manifests, err := ReadManifests(vrsPath, tool)
+	if err != nil {
+		return Manifest{}, false
+	}
+	for _, m := range manifests {
+		if m.Binary == binary {
+			return m, true
+		}
+	}
+	return Manifest{}, false
+}
+
+// GetBinaryPath returns the path of the installed binary of the tool version.
+func GetBinaryPath(vrsPath, tool, tag string) (string, error) {
+	m, err := ReadManifest(vrsPath, tool, tag)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vrsPath, tool, m.Binary), nil
+}
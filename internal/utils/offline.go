@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// ErrOffline is returned instead of accessing the network in offline mode.
+var ErrOffline = errors.New("offline mode")
+
+// IsOffline reports whether network access is disabled, through the "offline"
+// setting, the --offline flag or VRSR_OFFLINE.
+func IsOffline() bool {
+	return viper.GetBool("offline")
+}
+
+// CheckOnline returns an ErrOffline error describing what cannot be done in offline mode.
+func CheckOnline(action string) error {
+	if IsOffline() {
+		return fmt.Errorf("%w: cannot %s without network access", ErrOffline, action)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Version aliases accepted by ResolveVersion.
+const (
+	// AliasLatest is the newest version that is not a pre-release.
+	AliasLatest = "latest"
+	// AliasStable is a synonym of AliasLatest.
+	AliasStable = "stable"
+	// AliasPrerelease is the newest version, pre-releases included.
+	AliasPrerelease = "prerelease"
+)
+
+// ErrNoMatchingVersion is returned when no candidate satisfies the requested version.
+var ErrNoMatchingVersion = errors.New("no matching version")
+
+// ResolveVersion picks the version matching the input among the candidates and returns it as
+// originally spelled (e.g. the release tag). The input may be an exact version, an alias
+// ("latest" or "stable", "prerelease"), a partial version ("1.30" resolves to the newest 1.30 patch) or a
+// semver constraint ("~1.29.0", ">=3.14 <4").
+func ResolveVersion(input string, candidates []*semver.Version) (string, error) {
+	input = strings.TrimSpace(input)
+	sorted := make([]*semver.Version, len(candidates))
+	copy(sorted, candidates)
+	sort.Sort(sort.Reverse(semver.Collection(sorted)))
+
+	for _, v := range sorted {
+		if v.Original() == input {
+			return input, nil
+		}
+	}
+
+	switch strings.ToLower(input) {
+	case AliasPrerelease:
+		if len(sorted) > 0 {
+			return sorted[0].Original(), nil
+		}
+		return "", fmt.Errorf("%w for %q", ErrNoMatchingVersion, input)
+	case AliasLatest, AliasStable:
+		for _, v := range sorted {
+			if v.Prerelease() == "" {
+				return v.Original(), nil
+			}
+		}
+		return "", fmt.Errorf("%w for %q", ErrNoMatchingVersion, input)
+	}
+
+	c, err := semver.NewConstraint(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid version or constraint %q: %w", input, err)
+	}
+	for _, v := range sorted {
+		if c.Check(v) {
+			return v.Original(), nil
+		}
+	}
+	return "", fmt.Errorf("%w for %q", ErrNoMatchingVersion, input)
+}
+
+// IsExactVersion reports whether the input names a single version (e.g. "1.30.4" or "v1.31.0-rc.1")
+// rather than an alias, a partial version or a constraint.
+func IsExactVersion(input string) bool {
+	v, err := semver.StrictNewVersion(strings.TrimPrefix(strings.TrimSpace(input), "v"))
+	return err == nil && v != nil
+}
+
+// ResolveInstalled resolves the input against the installed versions of the tool.
+func ResolveInstalled(vrsPath, tool, input string) (string, error) {
+	versions, err := ListInstalledVersions(vrsPath, tool)
+	if err != nil {
+		return "", err
+	}
+	return ResolveVersion(input, versions)
+}
+
+// Upgrade levels accepted by NewestUpgrade, from the most to the least conservative.
+const (
+	// LevelPatch stays on the same minor series (e.g. 1.30.x).
+	LevelPatch = "patch"
+	// LevelMinor stays on the same major version (e.g. 1.x).
+	LevelMinor = "minor"
+	// LevelMajor allows any newer version.
+	LevelMajor = "major"
+)
+
+// NewestUpgrade returns the newest stable candidate greater than current within the given
+// level, or nil when there is none.
+func NewestUpgrade(current *semver.Version, candidates []*semver.Version, level string) *semver.Version {
+	var newest *semver.Version
+	for _, v := range candidates {
+		if v.Prerelease() != "" || !v.GreaterThan(current) {
+			continue
+		}
+		if level != LevelMajor && v.Major() != current.Major() {
+			continue
+		}
+		if level == LevelPatch && v.Minor() != current.Minor() {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	return newest
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ShimMarker identifies the dispatcher scripts written by vrsr in the bin path.
+const ShimMarker = "# vrsr-shim"
+
+// IsShim reports whether the file at path is a vrsr shim.
+func IsShim(path string) bool {
+	fi, err := os.Lstat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	head := make([]byte, 256)
+	n, _ := io.ReadFull(f, head)
+	return bytes.Contains(head[:n], []byte(ShimMarker))
+}
+
+// GetGlobalVersionPath returns the path of the file storing the global version of
+// the tool when shims are used.
+func GetGlobalVersionPath(binPath, tool string) string {
+	return filepath.Join(binPath, ".global", tool)
+}
+
+// ReadGlobalVersion returns the global version of the tool used by shims, if any.
+func ReadGlobalVersion(binPath, tool string) (string, error) {
+	content, err := os.ReadFile(GetGlobalVersionPath(binPath, tool))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// WriteGlobalVersion stores the global version of the tool used by shims.
+func WriteGlobalVersion(binPath, tool, vrs string) error {
+	p := GetGlobalVersionPath(binPath, tool)
+	if err := EnsurePathExists(filepath.Dir(p)); err != nil {
+		return err
+	}
+	return os.WriteFile(p, []byte(vrs+"\n"), 0644)
+}
+
+// RemoveGlobalVersion forgets the global version of the tool used by shims.
+func RemoveGlobalVersion(binPath, tool string) error {
+	err := os.Remove(GetGlobalVersionPath(binPath, tool))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -1,25 +1,35 @@
 package utils
 
 import (
-	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
-	"github.com/google/go-github/v78/github"
 	"github.com/spf13/viper"
 )
 
-// GetCachePath returns the path to the releases cache file.
+// GetCachePath returns the path to the releases cache of the tool.
+// Tools whose releases come from the same source (see RegisterReleasesSource) share it.
 func GetCachePath(tool string) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	binPath := filepath.Join(homeDir, ".vrsr", tool+"-releases.json")
-	return binPath, nil
+	if source := releasesSource(tool); source != "" {
+		return filepath.Join(homeDir, ".vrsr", "cache", filepath.FromSlash(source), "releases.json"), nil
+	}
+	return legacyCachePath(homeDir, tool), nil
+}
+
+// legacyCachePath returns the path to the releases cache of the tool keyed by its name,
+// used for tools without a known source.
+func legacyCachePath(homeDir, tool string) string {
+	return filepath.Join(homeDir, ".vrsr", tool+"-releases.json")
 }
 
 // GetDefaultBinPath returns the default bin path for in-use tool binary.
@@ -42,6 +52,33 @@ func GetDefaultVrsPath() (string, error) {
 	return binPath, nil
 }
 
+// GetToolsDefPath returns the path to the drop-in directory for tool definitions.
+func GetToolsDefPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".vrsr", "tools.d"), nil
+}
+
+// GetDownloadsPath returns the path to the cache of the partial downloads, kept to resume them.
+func GetDownloadsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".vrsr", "downloads"), nil
+}
+
+// GetCredentialsPath returns the path to the file storing the tokens saved by `vrsr auth login`.
+func GetCredentialsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".vrsr", "credentials.yaml"), nil
+}
+
 // EnsurePathExists ensures that the given path exists, creating it if necessary.
 func EnsurePathExists(path string) error {
 	return os.MkdirAll(path, os.ModePerm)
@@ -49,28 +86,13 @@ func EnsurePathExists(path string) error {
 
 // ListInstalledVersions lists all installed tool versions in the given vrsPath.
 func ListInstalledVersions(vrsPath, tool string) ([]*semver.Version, error) {
-	files, err := os.ReadDir(filepath.Join(vrsPath, tool))
+	manifests, err := ReadManifests(vrsPath, tool)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// vrsPath does not exist, return empty list
-			return []*semver.Version{}, nil
-		}
 		return nil, err
 	}
-	versions := make([]*semver.Version, 0)
-	for _, f := range files {
-		fileName := f.Name()
-		if f.IsDir() || !strings.HasPrefix(fileName, tool) {
-			// skip directories and non-tool files
-			continue
-		}
-		// by convention the file name is tool-VERSION
-		fv := strings.Split(fileName, "-")
-		if fv == nil || len(fv) != 2 {
-			// skip unexpected file names
-			continue
-		}
-		v, err := semver.NewVersion(fv[1])
+	versions := make([]*semver.Version, 0, len(manifests))
+	for _, m := range manifests {
+		v, err := semver.NewVersion(m.Tag)
 		if err == nil {
 			versions = append(versions, v)
 		}
@@ -81,7 +103,11 @@ func ListInstalledVersions(vrsPath, tool string) ([]*semver.Version, error) {
 }
 
 // GetVrsInUse returns the version of tool currently in use from the given binPath.
+// With shims the global version is read from the global version file instead.
 func GetVrsInUse(binPath, tool string) (string, error) {
+	if IsShim(filepath.Join(binPath, tool)) {
+		return ReadGlobalVersion(binPath, tool)
+	}
 	linkPath, err := filepath.EvalSymlinks(filepath.Join(binPath, tool))
 	if os.IsNotExist(err) || linkPath == "" {
 		return "", nil
@@ -89,19 +115,22 @@ func GetVrsInUse(binPath, tool string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	// the link points to vrs-path/<tool>/<binary>
 	baseName := filepath.Base(linkPath)
-	parts := strings.Split(baseName, "-")
-	if len(parts) == 2 {
-		return parts[1], nil
+	if m, ok := findManifest(filepath.Dir(filepath.Dir(linkPath)), tool, baseName); ok {
+		return m.Tag, nil
+	}
+	if vrs, ok := strings.CutPrefix(baseName, tool+"-"); ok {
+		return vrs, nil
 	}
 	return "", nil
 }
 
-// SemverFromReleases extracts semver.Version objects from GitHub releases.
-func SemverFromReleases(releases []*github.RepositoryRelease, includeDevel bool) []*semver.Version {
+// SemverFromReleases extracts semver.Version objects from the cached GitHub releases.
+func SemverFromReleases(releases []Release, includeDevel bool) []*semver.Version {
 	var versions []*semver.Version
 	for _, r := range releases {
-		v, err := semver.NewVersion(*r.TagName)
+		v, err := semver.NewVersion(r.Tag)
 		if err == nil {
 			if !includeDevel && v.Prerelease() != "" {
 				continue
@@ -117,13 +146,16 @@ func SemverFromReleases(releases []*github.RepositoryRelease, includeDevel bool)
 // IsToolInstalled checks if the specified version of the tool is installed in the vrsPath.
 func IsToolInstalled(tool, vrs string) bool {
 	vrsPath := viper.GetString("vrs-path")
-	fileNameWithPath := filepath.Join(vrsPath, tool, tool+"-"+vrs)
-	if _, err := os.Stat(fileNameWithPath); err == nil {
-		return true
-	} else if errors.Is(err, os.ErrNotExist) {
+	manifests, err := ReadManifests(vrsPath, tool)
+	if err != nil {
+		// err on the side of caution and say not installed
 		return false
 	}
-	// err on the side of caution and say not installed
+	for _, m := range manifests {
+		if m.Tag == vrs {
+			return true
+		}
+	}
 	return false
 }
 
@@ -143,3 +175,92 @@ func IsToolInUse(tool, vrs string) bool {
 	}
 	return false
 }
+
+// SetConfigSource points v at the given config file or, when empty, at the
+// default search paths ("./config.yaml" and "$HOME/.vrsr/config.yaml").
+func SetConfigSource(v *viper.Viper, cfgFile string) error {
+	if cfgFile != "" {
+		// Use config file from the flag.
+		v.SetConfigFile(cfgFile)
+		return nil
+	}
+	// Search for a config file in default locations.
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	// Search for a config file with the name "config" (without extension).
+	v.AddConfigPath(".")
+	v.AddConfigPath(filepath.Join(home, ".vrsr"))
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	return nil
+}
+
+// RemoveVersion deletes the installed binary of the tool version and returns its size.
+func RemoveVersion(vrsPath, tool, vrs string) (int64, error) {
+	m, err := ReadManifest(vrsPath, tool, vrs)
+	if err != nil {
+		// not adopted yet, fall back to the naming convention
+		m = NewManifest(tool, vrs)
+	}
+	fileName := filepath.Join(vrsPath, tool, m.Binary)
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Remove(fileName); err != nil {
+		return 0, err
+	}
+	return fi.Size(), RemoveManifest(vrsPath, tool, vrs)
+}
+
+// DiskUsage returns the space taken by the installed binaries of the tool. Binaries that no longer exist are skipped.
+func DiskUsage(vrsPath, tool string) (int64, error) {
+	manifests, err := ReadManifests(vrsPath, tool)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, m := range manifests {
+		fi, err := os.Stat(filepath.Join(vrsPath, tool, m.Binary))
+		if os.IsNotExist(err) {
+			// binary removed since the manifests were read, e.g. by a concurrent uninstall
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += fi.Size()
+	}
+	return total, nil
+}
+
+// InstalledAt returns when the tool version was installed.
+func InstalledAt(vrsPath, tool, vrs string) (time.Time, error) {
+	m, err := ReadManifest(vrsPath, tool, vrs)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.InstalledAt, nil
+}
+
+// ParseAge parses a duration which, besides the time.ParseDuration units,
+// may be expressed in days ("90d") or weeks ("2w").
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}